@@ -0,0 +1,39 @@
+// Lets galleries on the galleries index be dragged onto a collection. Dropping a gallery fills in and submits the
+// move form on its row, so it does exactly what picking a collection from that form does.
+(function () {
+    var dragged = null;
+
+    document.querySelectorAll('[data-gallery-id]').forEach(function (row) {
+        row.addEventListener('dragstart', function (e) {
+            dragged = row;
+            e.dataTransfer.effectAllowed = 'move';
+            e.dataTransfer.setData('text/plain', row.getAttribute('data-gallery-id'));
+        });
+        row.addEventListener('dragend', function () {
+            dragged = null;
+        });
+    });
+
+    document.querySelectorAll('[data-collection-id]').forEach(function (target) {
+        target.addEventListener('dragover', function (e) {
+            if (!dragged) {
+                return;
+            }
+            e.preventDefault();
+            target.classList.add('drop-target');
+        });
+        target.addEventListener('dragleave', function () {
+            target.classList.remove('drop-target');
+        });
+        target.addEventListener('drop', function (e) {
+            e.preventDefault();
+            target.classList.remove('drop-target');
+            if (!dragged) {
+                return;
+            }
+            var form = dragged.querySelector('.move-gallery-form');
+            form.elements['collection_id'].value = target.getAttribute('data-collection-id');
+            form.submit();
+        });
+    });
+})();
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 type PostgresConfig struct {
@@ -27,6 +28,9 @@ type Config struct {
 	HMACKey  string         `json:"hmac_key"`
 	Database PostgresConfig `json:"database"`
 	Mailgun  MailgunConfig  `json:"mailgun"`
+	// TrashRetentionDays is how long deleted galleries stay in
+	// the trash before they are deleted forever.
+	TrashRetentionDays int `json:"trash_retention_days"`
 }
 
 func (c PostgresConfig) Dialect() string {
@@ -54,13 +58,25 @@ func (c Config) IsProd() bool {
 	return c.Env == "prod"
 }
 
+// TrashRetention returns how long deleted galleries are kept
+// in the trash, falling back to 30 days if the config doesn't
+// say.
+func (c Config) TrashRetention() time.Duration {
+	days := c.TrashRetentionDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
 func DefaultConfig() Config {
 	return Config{
-		Port:     3000,
-		Env:      "dev",
-		Pepper:   "secret-random-string",
-		HMACKey:  "secret-hmac-key",
-		Database: DefaultPostgresConfig(),
+		Port:               3000,
+		Env:                "dev",
+		Pepper:             "secret-random-string",
+		HMACKey:            "secret-hmac-key",
+		Database:           DefaultPostgresConfig(),
+		TrashRetentionDays: 30,
 	}
 }
 
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const ShowCollection = "show_collection"
+
+// Collections handles the pages for collections of galleries. Access to the galleries inside a collection is still
+// decided by the galleries controller.
+type Collections struct {
+	ShowView  *views.View
+	cs        interfaces.CollectionServiceInt
+	gs        interfaces.GalleryServiceInt
+	is        interfaces.ImageServiceInt
+	galleries *Galleries
+	r         *mux.Router
+}
+
+// CollectionForm is used to create and update collections. An empty ParentID puts the collection at the top level.
+type CollectionForm struct {
+	Title      string `schema:"title"`
+	Visibility string `schema:"visibility"`
+	ParentID   string `schema:"parent_id"`
+}
+
+// collectionPage is what the collections/show template expects.
+type collectionPage struct {
+	*models.Collection
+	Breadcrumbs []models.Collection
+	Children    []models.Collection
+	Galleries   []models.Gallery
+	// All of the owner's collections, for picking a new parent. Only set for the owner.
+	Collections []models.Collection
+	IsOwner     bool
+}
+
+func NewCollections(cs interfaces.CollectionServiceInt, gs interfaces.GalleryServiceInt,
+	is interfaces.ImageServiceInt, galleries *Galleries, r *mux.Router) *Collections {
+	return &Collections{
+		ShowView:  views.NewView("bootstrap", "collections/show"),
+		cs:        cs,
+		gs:        gs,
+		is:        is,
+		galleries: galleries,
+		r:         r,
+	}
+}
+
+// Create adds a collection, inside another one if a parent is given.
+//
+// POST /collections
+func (c *Collections) Create(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	var form CollectionForm
+	if err := parseForm(r, &form); err != nil {
+		c.galleries.redirectToIndex(w, r, views.Alert{Level: views.AlertLvlError, Message: views.AlertMsgGeneric})
+		return
+	}
+	collection := models.Collection{
+		UserID:     user.ID,
+		Title:      form.Title,
+		Visibility: form.Visibility,
+		ParentID:   parseCollectionID(form.ParentID),
+	}
+	if err := c.cs.Create(&collection); err != nil {
+		var vd views.Data
+		vd.SetAlert(err)
+		c.galleries.redirectToIndex(w, r, *vd.Alert)
+		return
+	}
+	c.redirectToShow(w, r, &collection, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Collection created. Drag galleries onto it from your galleries page.",
+	})
+}
+
+// Show lists the collections and galleries inside a collection. Anybody may see a collection that isn't private, but
+// they only see the galleries in it that are at least as open as the collection itself.
+//
+// GET /collections/:id
+func (c *Collections) Show(w http.ResponseWriter, r *http.Request) {
+	collection, err := c.collectionByID(w, r)
+	if err != nil {
+		return
+	}
+	page, err := c.page(r, collection)
+	if err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	var vd views.Data
+	vd.Yield = page
+	c.ShowView.Render(w, r, vd)
+}
+
+// Update renames a collection, changes its visibility or moves it inside another collection.
+//
+// POST /collections/:id/update
+func (c *Collections) Update(w http.ResponseWriter, r *http.Request) {
+	collection, err := c.ownCollection(w, r)
+	if err != nil {
+		return
+	}
+	var form CollectionForm
+	if err := parseForm(r, &form); err != nil {
+		c.render(w, r, collection, err)
+		return
+	}
+	collection.Title = form.Title
+	collection.Visibility = form.Visibility
+	collection.ParentID = parseCollectionID(form.ParentID)
+	if err := c.cs.Update(collection); err != nil {
+		c.render(w, r, collection, err)
+		return
+	}
+	c.redirectToShow(w, r, collection, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Collection updated successfully!",
+	})
+}
+
+// Delete removes a collection. Everything that was in it moves up into its parent.
+//
+// POST /collections/:id/delete
+func (c *Collections) Delete(w http.ResponseWriter, r *http.Request) {
+	collection, err := c.ownCollection(w, r)
+	if err != nil {
+		return
+	}
+	if err := c.cs.Delete(collection.ID); err != nil {
+		c.render(w, r, collection, err)
+		return
+	}
+	c.galleries.redirectToIndex(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Collection deleted. Anything that was in it has been moved up a level.",
+	})
+}
+
+func (c *Collections) collectionByID(w http.ResponseWriter, r *http.Request) (*models.Collection, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusNotFound)
+		return nil, err
+	}
+	collection, err := c.cs.ByID(uint(id))
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			http.Error(w, "Collection not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return nil, err
+	}
+	if collection.IsPrivate() && !isOwner(r, collection) {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return nil, models.ErrNotFound
+	}
+	return collection, nil
+}
+
+// ownCollection is like collectionByID, but only lets the owner of the collection through.
+func (c *Collections) ownCollection(w http.ResponseWriter, r *http.Request) (*models.Collection, error) {
+	collection, err := c.collectionByID(w, r)
+	if err != nil {
+		return nil, err
+	}
+	if !isOwner(r, collection) {
+		http.Error(w, "You do not have permission to edit this collection", http.StatusForbidden)
+		return nil, errForbidden
+	}
+	return collection, nil
+}
+
+func isOwner(r *http.Request, collection *models.Collection) bool {
+	user := context.User(r.Context())
+	return user != nil && user.ID == collection.UserID
+}
+
+func (c *Collections) page(r *http.Request, collection *models.Collection) (*collectionPage, error) {
+	page := collectionPage{
+		Collection: collection,
+		IsOwner:    isOwner(r, collection),
+	}
+	crumbs, err := c.cs.Breadcrumbs(collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The template shows the collection itself after its breadcrumbs
+	if len(crumbs) > 0 {
+		crumbs = crumbs[:len(crumbs)-1]
+	}
+	children, err := c.cs.ByParentID(collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	galleries, err := c.gs.ByCollectionID(collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	if page.IsOwner {
+		page.Breadcrumbs = crumbs
+		page.Children = children
+		page.Galleries = galleries
+		page.Collections, err = c.cs.ByUserID(collection.UserID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, crumb := range crumbs {
+			if !crumb.IsPrivate() {
+				page.Breadcrumbs = append(page.Breadcrumbs, crumb)
+			}
+		}
+		for _, child := range children {
+			if models.AtLeastAsOpen(child.EffectiveVisibility(), collection.EffectiveVisibility()) {
+				page.Children = append(page.Children, child)
+			}
+		}
+		for _, gallery := range galleries {
+			if models.AtLeastAsOpen(gallery.EffectiveVisibility(), collection.EffectiveVisibility()) {
+				page.Galleries = append(page.Galleries, gallery)
+			}
+		}
+	}
+	loadCovers(c.is, page.Galleries, page.IsOwner)
+	return &page, nil
+}
+
+// render re-renders the collection page with an alert for the given error.
+func (c *Collections) render(w http.ResponseWriter, r *http.Request, collection *models.Collection, err error) {
+	var vd views.Data
+	vd.SetAlert(err)
+	page, pErr := c.page(r, collection)
+	if pErr != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = page
+	c.ShowView.Render(w, r, vd)
+}
+
+func (c *Collections) redirectToShow(w http.ResponseWriter, r *http.Request, collection *models.Collection,
+	alert views.Alert) {
+	url, err := c.r.Get(ShowCollection).URL("id", fmt.Sprintf("%v", collection.ID))
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
+
+// parseCollectionID turns a collection ID from a form into a *uint. Anything that isn't a number means no collection.
+func parseCollectionID(s string) *uint {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return nil
+	}
+	ret := uint(id)
+	return &ret
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/markdown"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	UserFeed = "user_feed"
+	// feedLength is the number of galleries in a feed
+	feedLength = 20
+)
+
+// Feeds serves the machine readable lists of public galleries: Atom feeds, so that people can follow photographers
+// without an account, and the sitemap for search engines.
+type Feeds struct {
+	gs        interfaces.GalleryServiceInt
+	is        interfaces.ImageServiceInt
+	us        interfaces.UserServiceInt
+	galleries *Galleries
+	r         *mux.Router
+}
+
+func NewFeeds(gs interfaces.GalleryServiceInt, is interfaces.ImageServiceInt, us interfaces.UserServiceInt,
+	galleries *Galleries, r *mux.Router) *Feeds {
+	return &Feeds{
+		gs:        gs,
+		is:        is,
+		us:        us,
+		galleries: galleries,
+		r:         r,
+	}
+}
+
+// The parts of an Atom feed (RFC 4287) that we use
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Links     []atomLink  `xml:"link"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Site is the feed of the galleries most recently published by anyone.
+//
+// GET /feed.atom
+func (f *Feeds) Site(w http.ResponseWriter, r *http.Request) {
+	galleries, err := f.gs.Published(0, feedLength)
+	if err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	feed := atomFeed{
+		ID:    absoluteURL(r, r.URL.Path),
+		Title: "New galleries on LensLocked.com",
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: absoluteURL(r, r.URL.Path)},
+			{Rel: "alternate", Type: "text/html", Href: absoluteURL(r, "/")},
+		},
+	}
+	f.serve(w, r, &feed, galleries, nil)
+}
+
+// User is the feed of the galleries a photographer has published most recently.
+//
+// GET /u/:username/feed.atom
+func (f *Feeds) User(w http.ResponseWriter, r *http.Request) {
+	user, err := f.us.ByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return
+	}
+	galleries, err := f.gs.Published(user.ID, feedLength)
+	if err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	author := f.author(r, user)
+	feed := atomFeed{
+		ID:     absoluteURL(r, r.URL.Path),
+		Title:  author.Name + " on LensLocked.com",
+		Author: author,
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: absoluteURL(r, r.URL.Path)},
+			{Rel: "alternate", Type: "text/html", Href: author.URI},
+		},
+	}
+	f.serve(w, r, &feed, galleries, user)
+}
+
+// serve adds the galleries to the feed and writes it out. If owner is set, every gallery belongs to them; otherwise
+// each entry gets its own author. The feed's updated time is when the most recently changed gallery in it was, and
+// together with an ETag of the feed it lets clients skip downloading the feed again when nothing has changed.
+func (f *Feeds) serve(w http.ResponseWriter, r *http.Request, feed *atomFeed, galleries []models.Gallery,
+	owner *models.User) {
+	var updated time.Time
+	authors := make(map[uint]*atomPerson)
+	if owner != nil {
+		updated = owner.UpdatedAt
+		authors[owner.ID] = feed.Author
+	}
+	for i := range galleries {
+		gallery := &galleries[i]
+		if gallery.ChangedAt().After(updated) {
+			updated = gallery.ChangedAt()
+		}
+		entry, err := f.entry(r, gallery)
+		if err != nil {
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		if owner == nil {
+			author, ok := authors[gallery.UserID]
+			if !ok {
+				if user, err := f.us.ByID(gallery.UserID); err == nil {
+					author = f.author(r, user)
+				}
+				authors[gallery.UserID] = author
+			}
+			entry.Author = author
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	sum := sha1.Sum(buf.Bytes())
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	// ServeContent answers If-None-Match and If-Modified-Since for us
+	http.ServeContent(w, r, "", updated, bytes.NewReader(buf.Bytes()))
+}
+
+// entry describes a gallery in a feed. Its ID is the gallery's numeric URL, which stays the same when its slug
+// changes. The gallery's cover image is linked as an enclosure, unless the gallery needs a password to see it.
+func (f *Feeds) entry(r *http.Request, gallery *models.Gallery) (atomEntry, error) {
+	showURL, err := f.galleries.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		return atomEntry{}, err
+	}
+	published := gallery.CreatedAt
+	if gallery.PublishAt != nil {
+		published = *gallery.PublishAt
+	}
+	entry := atomEntry{
+		ID:        absoluteURL(r, fmt.Sprintf("/galleries/%d", gallery.ID)),
+		Title:     gallery.Title,
+		Published: published.UTC().Format(time.RFC3339),
+		Updated:   gallery.ChangedAt().UTC().Format(time.RFC3339),
+		Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: absoluteURL(r, showURL.Path)}},
+	}
+	if gallery.Description != "" {
+		entry.Summary = &atomText{Type: "html", Body: string(markdown.Render(gallery.Description))}
+	}
+	if gallery.HasPassword() {
+		return entry, nil
+	}
+	images, err := f.is.ByGalleryID(gallery.ID)
+	if err != nil || len(images) == 0 {
+		return entry, nil
+	}
+	cover := &images[0]
+	enclosure := atomLink{
+		Rel:  "enclosure",
+		Type: mime.TypeByExtension(path.Ext(cover.Filename)),
+		Href: imageURL(r, cover),
+	}
+	if info, err := os.Stat(cover.RelativePath()); err == nil {
+		enclosure.Length = info.Size()
+	}
+	entry.Links = append(entry.Links, enclosure)
+	return entry, nil
+}
+
+// feedLink returns the <link> tag that lets feed readers find a user's feed from their pages. Users without a
+// username don't have a feed.
+func feedLink(router *mux.Router, r *http.Request, user *models.User) (views.MetaLink, bool) {
+	if user.Username == "" {
+		return views.MetaLink{}, false
+	}
+	feedURL, err := router.Get(UserFeed).URL("username", user.Username)
+	if err != nil {
+		return views.MetaLink{}, false
+	}
+	title := user.Name
+	if title == "" {
+		title = user.Username
+	}
+	return views.MetaLink{
+		Rel:   "alternate",
+		Type:  "application/atom+xml",
+		Href:  absoluteURL(r, feedURL.Path),
+		Title: title + " on LensLocked.com",
+	}, true
+}
+
+// author describes a user as the author of a feed or entry.
+func (f *Feeds) author(r *http.Request, user *models.User) *atomPerson {
+	author := atomPerson{Name: user.Name}
+	if author.Name == "" {
+		author.Name = user.Username
+	}
+	if user.Username != "" {
+		if profileURL, err := f.r.Get(ShowProfile).URL("username", user.Username); err == nil {
+			author.URI = absoluteURL(r, profileURL.Path)
+		}
+	}
+	return &author
+}
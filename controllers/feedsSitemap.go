@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"time"
+)
+
+// sitemapLength is the most URLs a sitemap may list.
+const sitemapLength = 50000
+
+// The parts of a sitemap (https://www.sitemaps.org/protocol.html) that we use
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap lists the pages search engines should know about: the home page, every public gallery and the profiles of
+// the photographers who have published them. Profiles without any public galleries have nothing to show, so they
+// are left out.
+//
+// GET /sitemap.xml
+func (f *Feeds) Sitemap(w http.ResponseWriter, r *http.Request) {
+	// Leave room for the home page and the profiles
+	galleries, err := f.gs.Published(0, sitemapLength/2)
+	if err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	set := sitemapURLSet{URLs: []sitemapURL{{Loc: absoluteURL(r, "/")}}}
+	usernames := make(map[uint]string)
+	profiles := make(map[uint]time.Time)
+	var owners []uint
+	for _, gallery := range galleries {
+		username, ok := usernames[gallery.UserID]
+		if !ok {
+			if user, err := f.us.ByID(gallery.UserID); err == nil {
+				username = user.Username
+			}
+			usernames[gallery.UserID] = username
+			owners = append(owners, gallery.UserID)
+		}
+		if gallery.ChangedAt().After(profiles[gallery.UserID]) {
+			profiles[gallery.UserID] = gallery.ChangedAt()
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     absoluteURL(r, galleryPath(f.r, &gallery, username)),
+			LastMod: gallery.ChangedAt().UTC().Format(time.RFC3339),
+		})
+	}
+	for _, id := range owners {
+		if usernames[id] == "" {
+			continue
+		}
+		profileURL, err := f.r.Get(ShowProfile).URL("username", usernames[id])
+		if err != nil {
+			continue
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     absoluteURL(r, profileURL.Path),
+			LastMod: profiles[id].UTC().Format(time.RFC3339),
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(set); err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	buf.WriteTo(w)
+}
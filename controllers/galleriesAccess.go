@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+)
+
+// galleryEditPage is what the galleries/edit template expects. Role decides which parts of the page are shown, and
+// only the owner gets to see the gallery's Stats.
+type galleryEditPage struct {
+	*models.Gallery
+	Role  string
+	Stats *models.GalleryStats
+}
+
+func (p galleryEditPage) CanEdit() bool {
+	return models.RoleAllows(p.Role, models.PermEdit)
+}
+
+func (p galleryEditPage) CanManage() bool {
+	return models.RoleAllows(p.Role, models.PermManage)
+}
+
+func (g *Galleries) editPage(r *http.Request, gallery *models.Gallery) galleryEditPage {
+	page := galleryEditPage{
+		Gallery: gallery,
+		Role:    g.role(r, gallery),
+	}
+	if page.CanManage() {
+		// The page is still useful without stats, so don't fail it for them
+		stats, err := g.sts.Summary(gallery.ID, statsDays)
+		if err != nil {
+			log.Println(err)
+		}
+		page.Stats = stats
+	}
+	return page
+}
+
+// role returns the role the current user has on the gallery, or an empty string if they have none.
+func (g *Galleries) role(r *http.Request, gallery *models.Gallery) string {
+	user := context.User(r.Context())
+	if user == nil {
+		return ""
+	}
+	if user.ID == gallery.UserID {
+		return models.RoleOwner
+	}
+	return g.cs.Role(gallery.ID, user.ID)
+}
+
+// canView reports whether the visitor making the request may see the gallery and its images. Outside of its publishing
+// schedule a gallery is only visible to its owner and collaborators, whatever share links or password it has.
+func (g *Galleries) canView(r *http.Request, gallery *models.Gallery) bool {
+	if models.RoleAllows(g.role(r, gallery), models.PermView) {
+		return true
+	}
+	if gallery.Scheduled() || gallery.Expired() {
+		return false
+	}
+	return g.admitted(r, gallery)
+}
+
+// admitted reports whether a visitor without a role on the gallery would be let in while it is published.
+func (g *Galleries) admitted(r *http.Request, gallery *models.Gallery) bool {
+	// Share links are handed out by the owner, so they let visitors in regardless of any password or visibility.
+	if g.sharedWith(r, gallery) {
+		return true
+	}
+	// A password takes precedence over visibility: visitors who know it get in, even to a private gallery, and
+	// everybody else stays out.
+	if gallery.HasPassword() {
+		cookie, err := r.Cookie(unlockCookie(gallery.ID))
+		return err == nil && g.gs.Unlocked(gallery, cookie.Value)
+	}
+	return gallery.LiveVisibility() != models.VisibilityPrivate
+}
+
+// authorize looks up the gallery in the URL and makes sure the current visitor has the given permission on it. Every
+// gallery handler goes through here. Visitors who can't even see the gallery are told it doesn't exist so that we
+// don't leak which IDs are in use.
+func (g *Galleries) authorize(w http.ResponseWriter, r *http.Request, perm models.Permission) (*models.Gallery, error) {
+	gallery, err := g.galleryByID(w, r)
+	if err != nil {
+		// galleryByID handles errors
+		return nil, err
+	}
+	if models.RoleAllows(g.role(r, gallery), perm) {
+		return gallery, nil
+	}
+	if !g.canView(r, gallery) {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return nil, models.ErrNotFound
+	}
+	if perm == models.PermView {
+		return gallery, nil
+	}
+	http.Error(w, "You do not have permission to do that with this gallery", http.StatusForbidden)
+	return nil, errForbidden
+}
+
+// errForbidden is returned by authorize when it has already responded with a 403.
+var errForbidden = errors.New("controllers: forbidden")
+
+// ImageServe serves an image from disk, applying the same access rules as the gallery it belongs to.
+//
+// GET /images/galleries/:id/:filename
+func (g *Galleries) ImageServe(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	filename := vars["filename"]
+	if filename != filepath.Base(filename) || strings.HasPrefix(filename, ".") {
+		http.NotFound(w, r)
+		return
+	}
+	gallery, err := g.gs.ByID(uint(id))
+	if err != nil || !g.canView(r, gallery) {
+		http.NotFound(w, r)
+		return
+	}
+	img := models.Image{
+		GalleryID: gallery.ID,
+		Filename:  filename,
+	}
+	serveRegularFile(w, r, img.RelativePath(), func(info os.FileInfo) {
+		current, err := g.is.CurrentVersion(gallery.ID, filename)
+		if err != nil {
+			log.Println(err)
+		}
+		setImageCaching(w, r, gallery, current)
+		// Replacing or restoring a version changes both of these, so browsers that check their copy find out it is
+		// stale
+		w.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, current, info.ModTime().UnixNano()))
+		if r.URL.Query().Get("download") != "" {
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			g.recordStat(r, gallery, filename, g.sts.RecordDownload)
+		} else {
+			g.recordStat(r, gallery, filename, g.sts.RecordView)
+		}
+	})
+}
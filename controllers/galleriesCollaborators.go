@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+type CollaboratorForm struct {
+	Email string `schema:"email"`
+	Role  string `schema:"role"`
+}
+
+// collaboratorsPage is what the galleries/collaborators template expects.
+type collaboratorsPage struct {
+	*models.Gallery
+	Collaborators []models.Collaborator
+}
+
+// sharedGallery is a gallery someone else owns that the user has been invited to work on.
+type sharedGallery struct {
+	models.Gallery
+	Role string
+}
+
+// sharedWithUser returns every gallery the user has accepted an invitation to.
+func (g *Galleries) sharedWithUser(user *models.User) ([]sharedGallery, error) {
+	collaborations, err := g.cs.ByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]sharedGallery, 0, len(collaborations))
+	for _, c := range collaborations {
+		gallery, err := g.gs.ByID(c.GalleryID)
+		if err == models.ErrNotFound {
+			// The gallery has been deleted since
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, sharedGallery{Gallery: *gallery, Role: c.Role})
+	}
+	return ret, nil
+}
+
+func (g *Galleries) renderCollaborators(w http.ResponseWriter, r *http.Request, vd views.Data,
+	gallery *models.Gallery) {
+	collaborators, err := g.cs.ByGalleryID(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = collaboratorsPage{
+		Gallery:       gallery,
+		Collaborators: collaborators,
+	}
+	g.CollabView.Render(w, r, vd)
+}
+
+// collaboratorByID looks up the collaborator in the URL and makes sure they belong to the gallery.
+func (g *Galleries) collaboratorByID(w http.ResponseWriter, r *http.Request,
+	gallery *models.Gallery) (*models.Collaborator, error) {
+	cid, err := strconv.Atoi(mux.Vars(r)["cid"])
+	if err != nil {
+		http.Error(w, "Collaborator not found", http.StatusNotFound)
+		return nil, err
+	}
+	collaborator, err := g.cs.ByID(uint(cid))
+	if err != nil || collaborator.GalleryID != gallery.ID {
+		http.Error(w, "Collaborator not found", http.StatusNotFound)
+		return nil, models.ErrNotFound
+	}
+	return collaborator, nil
+}
+
+// Collaborators lists everyone invited to work on a gallery.
+//
+// GET /galleries/:id/collaborators
+func (g *Galleries) Collaborators(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	g.renderCollaborators(w, r, views.Data{}, gallery)
+}
+
+// InviteCollaborator invites someone to work on a gallery by email.
+//
+// POST /galleries/:id/collaborators
+func (g *Galleries) InviteCollaborator(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form CollaboratorForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderCollaborators(w, r, vd, gallery)
+		return
+	}
+	collaborator := models.Collaborator{
+		GalleryID: gallery.ID,
+		Email:     form.Email,
+		Role:      form.Role,
+	}
+	if err := g.cs.Create(&collaborator); err != nil {
+		vd.SetAlert(err)
+		g.renderCollaborators(w, r, vd, gallery)
+		return
+	}
+
+	url, err := g.r.Get(AcceptInvite).URL("token", collaborator.Token)
+	if err == nil {
+		user := context.User(r.Context())
+		err = g.emailer.Invite(collaborator.Email, user.Name, gallery, collaborator.Role, absoluteURL(r, url.Path))
+	}
+	if err != nil {
+		// The invitation exists, but nobody will ever receive it. Remove it so the owner can try again.
+		log.Println(err)
+		g.cs.Delete(collaborator.ID)
+		vd.AlertError("We couldn't send the invitation email. Please try again.")
+		g.renderCollaborators(w, r, vd, gallery)
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Invitation sent to " + collaborator.Email + ".",
+	}
+	g.renderCollaborators(w, r, vd, gallery)
+}
+
+// UpdateCollaborator changes a collaborator's role.
+//
+// POST /galleries/:id/collaborators/:cid/update
+func (g *Galleries) UpdateCollaborator(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	collaborator, err := g.collaboratorByID(w, r, gallery)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form CollaboratorForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderCollaborators(w, r, vd, gallery)
+		return
+	}
+	collaborator.Role = form.Role
+	if err := g.cs.Update(collaborator); err != nil {
+		vd.SetAlert(err)
+	} else {
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "Collaborator updated.",
+		}
+	}
+	g.renderCollaborators(w, r, vd, gallery)
+}
+
+// RemoveCollaborator takes away a collaborator's access, or withdraws an invitation that hasn't been accepted yet.
+//
+// POST /galleries/:id/collaborators/:cid/delete
+func (g *Galleries) RemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	collaborator, err := g.collaboratorByID(w, r, gallery)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	if err := g.cs.Delete(collaborator.ID); err != nil {
+		vd.SetAlert(err)
+	} else {
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: collaborator.Email + " no longer has access to this gallery.",
+		}
+	}
+	g.renderCollaborators(w, r, vd, gallery)
+}
+
+// AcceptInvite gives the signed in user the role they were invited with.
+//
+// GET /invitations/:token
+func (g *Galleries) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	collaborator, err := g.cs.Accept(mux.Vars(r)["token"], user)
+	if err != nil {
+		if err == models.ErrTokenInvalid {
+			http.Error(w, "This invitation is not valid. It may have been withdrawn, accepted by someone else, "+
+				"or sent to another email address.", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	route := ShowGallery
+	if models.RoleAllows(collaborator.Role, models.PermUpload) {
+		route = EditGallery
+	}
+	gallery, err := g.gs.ByID(collaborator.GalleryID)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	url, err := g.galleryURL(gallery, route)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Invitation accepted. You are now a " + collaborator.Role + " on this gallery.",
+	})
+}
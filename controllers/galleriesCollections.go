@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// MoveGalleryForm is used to put a gallery in a collection. An empty CollectionID takes it out of any collection.
+type MoveGalleryForm struct {
+	CollectionID string `schema:"collection_id"`
+}
+
+// breadcrumbs returns the collections the gallery is in, starting from the top. Visitors who only got in with a share
+// link or a password aren't shown private collections, since even their titles may say more than the owner wants.
+func (g *Galleries) breadcrumbs(r *http.Request, gallery *models.Gallery) []models.Collection {
+	if gallery.CollectionID == nil {
+		return nil
+	}
+	crumbs, err := g.cols.Breadcrumbs(*gallery.CollectionID)
+	if err != nil {
+		return nil
+	}
+	if models.RoleAllows(g.role(r, gallery), models.PermView) {
+		return crumbs
+	}
+	var ret []models.Collection
+	for _, c := range crumbs {
+		if !c.IsPrivate() {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// MoveGallery puts a gallery in one of its owner's collections. It is what the drag and drop on the galleries index
+// submits, as well as the form it falls back to.
+//
+// POST /galleries/:id/collection
+func (g *Galleries) MoveGallery(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var form MoveGalleryForm
+	if err := parseForm(r, &form); err != nil {
+		g.redirectToIndex(w, r, views.Alert{Level: views.AlertLvlError, Message: views.AlertMsgGeneric})
+		return
+	}
+	gallery.CollectionID = nil
+	if id := parseCollectionID(form.CollectionID); id != nil {
+		collection, err := g.cols.ByID(*id)
+		if err != nil || collection.UserID != gallery.UserID {
+			g.redirectToIndex(w, r, views.Alert{
+				Level:   views.AlertLvlError,
+				Message: models.ErrCollectionInvalid.Public(),
+			})
+			return
+		}
+		gallery.CollectionID = &collection.ID
+	}
+	if err := g.gs.Update(gallery); err != nil {
+		var vd views.Data
+		vd.SetAlert(err)
+		g.redirectToIndex(w, r, *vd.Alert)
+		return
+	}
+	g.redirectToIndex(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: gallery.Title + " has been moved.",
+	})
+}
+
+// redirectToIndex sends the user back to the galleries index with the given alert.
+func (g *Galleries) redirectToIndex(w http.ResponseWriter, r *http.Request, alert views.Alert) {
+	url, err := g.r.Get(IndexGalleries).URL()
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
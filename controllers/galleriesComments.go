@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	// A visitor can leave maxComments comments, across every gallery, every commentWindow.
+	maxComments   = 5
+	commentWindow = 10 * time.Minute
+)
+
+// CommentForm is used to comment on a gallery, or on one of its images when Filename is set.
+type CommentForm struct {
+	Name     string `schema:"name"`
+	Body     string `schema:"body"`
+	Filename string `schema:"filename"`
+}
+
+// GalleryComments returns the comments on the gallery as a whole.
+func (p galleryPage) GalleryComments() []models.Comment {
+	return p.CommentsOn("")
+}
+
+// CommentsOn returns the comments on the image with the given filename.
+func (p galleryPage) CommentsOn(filename string) []models.Comment {
+	var ret []models.Comment
+	for _, c := range p.Comments {
+		if c.Filename == filename {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
+
+// commentItem is what the comment template expects: the comment, and whether to show the moderation buttons.
+type commentItem struct {
+	models.Comment
+	CanModerate bool
+}
+
+// CommentData returns what a template needs to render the given comment.
+func (p galleryPage) CommentData(c models.Comment) commentItem {
+	return commentItem{
+		Comment:     c,
+		CanModerate: p.CanModerate,
+	}
+}
+
+// comments loads the comments shown on the gallery page. Whoever can moderate them also sees the ones waiting for
+// approval.
+func (g *Galleries) comments(r *http.Request, gallery *models.Gallery) []models.Comment {
+	if !gallery.CommentsEnabled() {
+		return nil
+	}
+	all := models.RoleAllows(g.role(r, gallery), models.PermManage)
+	comments, err := g.coms.ByGalleryID(gallery.ID, all)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return comments
+}
+
+// PostComment adds a comment to a gallery or one of its images. Anyone who can see the gallery can comment, as long
+// as the owner has turned comments on.
+//
+// POST /galleries/:id/comments
+func (g *Galleries) PostComment(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	if !gallery.CommentsEnabled() {
+		g.renderShow(w, r, gallery, models.ErrCommentsClosed)
+		return
+	}
+	var form CommentForm
+	if err := parseForm(r, &form); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	if form.Filename != "" && !hasImage(gallery, form.Filename) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	if !g.commentLimiter.Allow(clientIP(r)) {
+		g.renderShow(w, r, gallery, models.ErrTooManyComments)
+		return
+	}
+
+	canModerate := models.RoleAllows(g.role(r, gallery), models.PermManage)
+	comment := models.Comment{
+		GalleryID: gallery.ID,
+		Filename:  form.Filename,
+		Name:      form.Name,
+		Body:      form.Body,
+		Approved:  gallery.CommentMode == models.CommentsOpen || canModerate,
+	}
+	if user := context.User(r.Context()); user != nil {
+		comment.UserID = &user.ID
+		if comment.Name == "" {
+			comment.Name = user.Name
+		}
+	}
+	if err := g.coms.Create(&comment); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+
+	// The comment is saved by now, so a failed email is only logged
+	if !canModerate {
+		owner, err := g.us.ByID(gallery.UserID)
+		if err == nil {
+			err = g.emailer.CommentPosted(owner.Email, gallery, &comment)
+		}
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	alert := views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Thanks for your comment!",
+	}
+	if !comment.Approved {
+		alert.Message = "Thanks for your comment! It will appear once the photographer has approved it."
+	}
+	g.redirectToShow(w, r, gallery, alert)
+}
+
+// ApproveComment shows a comment that was waiting for approval.
+//
+// POST /galleries/:id/comments/:cid/approve
+func (g *Galleries) ApproveComment(w http.ResponseWriter, r *http.Request) {
+	gallery, comment, err := g.moderateComment(w, r)
+	if err != nil {
+		return
+	}
+	if err := g.coms.Approve(comment); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "The comment has been approved.",
+	})
+}
+
+// DeleteComment removes a comment.
+//
+// POST /galleries/:id/comments/:cid/delete
+func (g *Galleries) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	gallery, comment, err := g.moderateComment(w, r)
+	if err != nil {
+		return
+	}
+	if err := g.coms.Delete(comment.ID); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "The comment has been deleted.",
+	})
+}
+
+// moderateComment looks up the comment in the URL, making sure it belongs to the gallery in the URL and that the
+// current user may moderate the gallery's comments.
+func (g *Galleries) moderateComment(w http.ResponseWriter, r *http.Request) (*models.Gallery, *models.Comment, error) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return nil, nil, err
+	}
+	cid, err := strconv.Atoi(mux.Vars(r)["cid"])
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return nil, nil, err
+	}
+	comment, err := g.coms.ByID(uint(cid))
+	if err == nil && comment.GalleryID != gallery.ID {
+		err = models.ErrNotFound
+	}
+	if err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return nil, nil, err
+	}
+	return gallery, comment, nil
+}
+
+// hasImage reports whether the gallery has an image with the given filename.
+func hasImage(gallery *models.Gallery, filename string) bool {
+	for _, img := range gallery.Images {
+		if img.Filename == filename {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,17 @@
 package controllers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 
 	"lenslocked.com/context"
+	"lenslocked.com/email"
 	"lenslocked.com/interfaces"
 	"lenslocked.com/models"
+	"lenslocked.com/ratelimit"
 	"lenslocked.com/views"
 )
 
@@ -17,32 +19,111 @@ const (
 	IndexGalleries  = "index_galleries"
 	ShowGallery     = "show_gallery"
 	EditGallery     = "edit_gallery"
+	ShowGalleryByID = "show_gallery_by_id"
+	EditGalleryByID = "edit_gallery_by_id"
+	AcceptInvite    = "accept_invite"
+	VisitShareLink  = "visit_share_link"
+	TrashGalleries  = "trash_galleries"
+	AcceptTransfer  = "accept_transfer"
 	maxMultipartMem = 1 << 20 // 1 megabyte
 )
 
 type Galleries struct {
-	New       *views.View
-	ShowView  *views.View
-	EditView  *views.View
-	IndexView *views.View
-	gs        interfaces.GalleryServiceInt
-	is        interfaces.ImageServiceInt
-	r         *mux.Router
+	New            *views.View
+	ShowView       *views.View
+	EditView       *views.View
+	IndexView      *views.View
+	SelectionsView *views.View
+	UnlockView     *views.View
+	ShareLinksView *views.View
+	CollabView     *views.View
+	TrashView      *views.View
+	ExpiredView    *views.View
+	FavouritesView *views.View
+	TransferView   *views.View
+	AcceptView     *views.View
+	EmbedView      *views.View
+	GuestLinksView *views.View
+	GuestView      *views.View
+	VersionsView   *views.View
+	gs             interfaces.GalleryServiceInt
+	is             interfaces.ImageServiceInt
+	ss             interfaces.SelectionServiceInt
+	sls            interfaces.ShareLinkServiceInt
+	cs             interfaces.CollaboratorServiceInt
+	cols           interfaces.CollectionServiceInt
+	sts            interfaces.StatServiceInt
+	coms           interfaces.CommentServiceInt
+	likes          interfaces.LikeServiceInt
+	trs            interfaces.TransferServiceInt
+	guests         interfaces.GuestServiceInt
+	us             interfaces.UserServiceInt
+	emailer        *email.Client
+	r              *mux.Router
+	unlockLimiter  *ratelimit.Limiter
+	commentLimiter *ratelimit.Limiter
+	proofLimiter   *ratelimit.Limiter
 }
 
 type GalleryForm struct {
-	Title string `schema:"title"`
+	Title        string `schema:"title"`
+	Slug         string `schema:"slug"`
+	Description  string `schema:"description"`
+	Layout       string `schema:"layout"`
+	Columns      int    `schema:"columns"`
+	Proofing     bool   `schema:"proofing"`
+	Visibility   string `schema:"visibility"`
+	Tags         string `schema:"tags"`
+	PublishAt    string `schema:"publish_at"`
+	ExpireAt     string `schema:"expire_at"`
+	NotifyExpiry bool   `schema:"notify_expiry"`
+	CommentMode  string `schema:"comment_mode"`
 }
 
-func NewGalleries(gs interfaces.GalleryServiceInt, is interfaces.ImageServiceInt, r *mux.Router) *Galleries {
+type CaptionForm struct {
+	Caption string `schema:"caption"`
+}
+
+func NewGalleries(gs interfaces.GalleryServiceInt, is interfaces.ImageServiceInt, ss interfaces.SelectionServiceInt,
+	sls interfaces.ShareLinkServiceInt, cs interfaces.CollaboratorServiceInt, cols interfaces.CollectionServiceInt,
+	sts interfaces.StatServiceInt, coms interfaces.CommentServiceInt, likes interfaces.LikeServiceInt,
+	trs interfaces.TransferServiceInt, guests interfaces.GuestServiceInt, us interfaces.UserServiceInt,
+	emailer *email.Client, r *mux.Router) *Galleries {
 	return &Galleries{
-		New:       views.NewView("bootstrap", "galleries/new"),
-		ShowView:  views.NewView("bootstrap", "galleries/show"),
-		EditView:  views.NewView("bootstrap", "galleries/edit"),
-		IndexView: views.NewView("bootstrap", "galleries/index"),
-		gs:        gs,
-		is:        is,
-		r:         r,
+		New:            views.NewView("bootstrap", "galleries/new"),
+		ShowView:       views.NewView("bootstrap", "galleries/show", "galleries/layouts"),
+		EditView:       views.NewView("bootstrap", "galleries/edit"),
+		IndexView:      views.NewView("bootstrap", "galleries/index"),
+		SelectionsView: views.NewView("bootstrap", "galleries/selections"),
+		UnlockView:     views.NewView("bootstrap", "galleries/unlock"),
+		ShareLinksView: views.NewView("bootstrap", "galleries/links"),
+		CollabView:     views.NewView("bootstrap", "galleries/collaborators"),
+		TrashView:      views.NewView("bootstrap", "galleries/trash"),
+		ExpiredView:    views.NewView("bootstrap", "galleries/expired"),
+		FavouritesView: views.NewView("bootstrap", "galleries/favourites"),
+		TransferView:   views.NewView("bootstrap", "galleries/transfer"),
+		AcceptView:     views.NewView("bootstrap", "galleries/accept"),
+		EmbedView:      views.NewView("embed", "galleries/embed", "galleries/layouts"),
+		GuestLinksView: views.NewView("bootstrap", "galleries/guests"),
+		GuestView:      views.NewView("bootstrap", "galleries/guest_upload"),
+		VersionsView:   views.NewView("bootstrap", "galleries/versions"),
+		gs:             gs,
+		is:             is,
+		ss:             ss,
+		sls:            sls,
+		cs:             cs,
+		cols:           cols,
+		sts:            sts,
+		coms:           coms,
+		likes:          likes,
+		trs:            trs,
+		guests:         guests,
+		us:             us,
+		emailer:        emailer,
+		r:              r,
+		unlockLimiter:  ratelimit.New(maxUnlockAttempts, unlockAttemptWindow),
+		commentLimiter: ratelimit.New(maxComments, commentWindow),
+		proofLimiter:   ratelimit.New(maxSelections, selectionWindow),
 	}
 }
 
@@ -67,8 +148,7 @@ func (g *Galleries) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reconstruct the url using the named EditGallery route
-	// URL takes the parameter 'id' and gives in value of the gallery ID
-	url, err := g.r.Get(EditGallery).URL("id", strconv.Itoa(int(gallery.ID)))
+	url, err := g.galleryURL(&gallery, EditGallery)
 	if err != nil {
 		http.Redirect(w, r, "/", http.StatusFound)
 		return
@@ -77,52 +157,93 @@ func (g *Galleries) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 // Renders the edit form
+// GET /u/:username/:slug/edit
 // GET /galleries/:id/edit
 func (g *Galleries) Edit(w http.ResponseWriter, r *http.Request) {
-	gallery, err := g.galleryByID(w, r)
+	// Contributors need the edit page to upload images, so they can see it too
+	gallery, err := g.authorize(w, r, models.PermUpload)
 	if err != nil {
-		// galleryByID handles errors
+		// authorize handles errors
 		return
 	}
-
-	// Only the creator of the gallery can edit it
-	user := context.User(r.Context())
-	if gallery.UserID != user.ID {
-		http.Error(w, "You do not have permission to edit this gallery", http.StatusForbidden)
+	if g.redirectToSlug(w, r, gallery, EditGallery) {
 		return
 	}
 	var vd views.Data
-	vd.Yield = gallery
+	vd.Yield = g.editPage(r, gallery)
 	g.EditView.Render(w, r, vd)
 }
 
+// galleryIndexPage is what the galleries/index template expects.
+type galleryIndexPage struct {
+	Galleries   []models.Gallery
+	Collections []models.Collection
+	Pagination  *views.Pagination
+	Filter      models.GalleryQuery
+	Shared      []sharedGallery
+	Query       string
+	Results     []models.GallerySearchResult
+}
+
+// Index lists the user's galleries a page at a time. The sort, order, visibility and tag query parameters sort and
+// filter the list, and q searches it.
+//
 // GET /galleries
 func (g *Galleries) Index(w http.ResponseWriter, r *http.Request) {
 	user := context.User(r.Context())
-	galleries, err := g.gs.ByUserID(user.ID)
+	params := r.URL.Query()
+	var vd views.Data
+	page := galleryIndexPage{
+		Filter: models.GalleryQuery{
+			UserID:     user.ID,
+			Sort:       params.Get("sort"),
+			Desc:       params.Get("order") != "asc",
+			Visibility: params.Get("visibility"),
+			Tag:        params.Get("tag"),
+			After:      params.Get("after"),
+			Before:     params.Get("before"),
+		},
+		Query: params.Get("q"),
+	}
+	galleries, err := g.gs.Page(page.Filter)
+	switch err {
+	case nil:
+		page.Galleries = galleries.Galleries
+		page.Pagination = views.NewPagination(r.URL, galleries.PrevCursor, galleries.NextCursor)
+	case models.ErrSortInvalid, models.ErrCursorInvalid, models.ErrVisibilityInvalid:
+		vd.SetAlert(err)
+	default:
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	page.Collections, err = g.cols.ByUserID(user.ID)
 	if err != nil {
 		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
 		return
 	}
-	var vd views.Data
-	vd.Yield = galleries
+	page.Shared, err = g.sharedWithUser(user)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	page.Results, err = g.gs.Search(user.ID, page.Query)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = page
 	g.IndexView.Render(w, r, vd)
 }
 
 // POST /galleries/:id/update
 func (g *Galleries) Update(w http.ResponseWriter, r *http.Request) {
-	gallery, err := g.galleryByID(w, r)
+	gallery, err := g.authorize(w, r, models.PermEdit)
 	if err != nil {
 		return
 	}
-	user := context.User(r.Context())
-	if gallery.UserID != user.ID {
-		http.Error(w, "Gallery not found", http.StatusNotFound)
-		return
-	}
 
 	var vd views.Data
-	vd.Yield = gallery
+	vd.Yield = g.editPage(r, gallery)
 	var form GalleryForm
 	if err := parseForm(r, &form); err != nil {
 		// If there is an error we are going to render the EditView again with an alert message.
@@ -131,6 +252,22 @@ func (g *Galleries) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	gallery.Title = form.Title
+	gallery.Description = form.Description
+	gallery.Tags = strings.Split(form.Tags, ",")
+	gallery.Layout = form.Layout
+	gallery.Columns = form.Columns
+	// Editors may rename a gallery, but only its owner decides who gets to see it
+	if models.RoleAllows(g.role(r, gallery), models.PermManage) {
+		gallery.Proofing = form.Proofing
+		gallery.Visibility = form.Visibility
+		gallery.CommentMode = form.CommentMode
+		gallery.Slug = form.Slug
+		if err := setSchedule(gallery, form); err != nil {
+			vd.SetAlert(err)
+			g.EditView.Render(w, r, vd)
+			return
+		}
+	}
 	err = g.gs.Update(gallery)
 	if err != nil {
 		vd.SetAlert(err)
@@ -145,46 +282,34 @@ func (g *Galleries) Update(w http.ResponseWriter, r *http.Request) {
 
 // POST /galleries/:id/delete
 func (g *Galleries) Delete(w http.ResponseWriter, r *http.Request) {
-	gallery, err := g.galleryByID(w, r)
+	gallery, err := g.authorize(w, r, models.PermManage)
 	if err != nil {
 		return
 	}
-	user := context.User(r.Context())
-	if gallery.UserID != user.ID {
-		http.Error(w, "You do not have permission to edit this gallery", http.StatusForbidden)
-		return
-	}
 
 	var vd views.Data
 	err = g.gs.Delete(gallery.ID)
 	if err != nil {
 		vd.SetAlert(err)
-		vd.Yield = gallery
+		vd.Yield = g.editPage(r, gallery)
 		g.EditView.Render(w, r, vd)
 		return
 	}
-	url, err := g.r.Get(IndexGalleries).URL()
-	if err != nil {
-		http.Redirect(w, r, "/", http.StatusFound)
-		return
-	}
-	http.Redirect(w, r, url.Path, http.StatusFound)
+	g.redirectToIndex(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: gallery.Title + " has been moved to the trash. You can restore it from there.",
+	})
 }
 
 // POST /galleries/:id/images
 func (g *Galleries) ImageUpload(w http.ResponseWriter, r *http.Request) {
-	gallery, err := g.galleryByID(w, r)
+	gallery, err := g.authorize(w, r, models.PermUpload)
 	if err != nil {
 		return
 	}
-	user := context.User(r.Context())
-	if gallery.UserID != user.ID {
-		http.Error(w, "Gallery not found", http.StatusNotFound)
-		return
-	}
 
 	var vd views.Data
-	vd.Yield = gallery
+	vd.Yield = g.editPage(r, gallery)
 	err = r.ParseMultipartForm(maxMultipartMem)
 	if err != nil {
 		vd.SetAlert(err)
@@ -217,7 +342,7 @@ func (g *Galleries) ImageUpload(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	url, err := g.r.Get(EditGallery).URL("id", fmt.Sprintf("%v", gallery.ID))
+	url, err := g.galleryURL(gallery, EditGallery)
 	if err != nil {
 		http.Redirect(w, r, "/galleries", http.StatusFound)
 		return
@@ -225,26 +350,52 @@ func (g *Galleries) ImageUpload(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, url.Path, http.StatusFound)
 }
 
+// GET /u/:username/:slug
+// GET /galleries/:id
 func (g *Galleries) Show(w http.ResponseWriter, r *http.Request) {
 	gallery, err := g.galleryByID(w, r)
 	if err != nil {
-		http.Error(w, "Invalid gallery ID", http.StatusNotFound)
+		// galleryByID handles errors
 		return
 	}
+	if !g.canView(r, gallery) {
+		switch {
+		case gallery.Expired() && (gallery.HasPassword() || g.admitted(r, gallery)):
+			g.ExpiredView.RenderStatus(w, r, http.StatusGone, gallery)
+		case gallery.HasPassword() && !gallery.Scheduled() && !gallery.Expired():
+			g.UnlockView.Render(w, r, gallery)
+		default:
+			http.Error(w, "Gallery not found", http.StatusNotFound)
+		}
+		return
+	}
+	if g.redirectToSlug(w, r, gallery, ShowGallery) {
+		return
+	}
+	g.recordStat(r, gallery, "", g.sts.RecordView)
 	var vd views.Data
-	vd.Yield = gallery
+	vd.Meta = g.galleryMeta(r, gallery)
+	vd.Yield = g.showPage(r, gallery)
 	g.ShowView.Render(w, r, vd)
 }
 
+// galleryByID looks up the gallery in the URL, which is identified either by its ID or by its owner's username and its
+// slug.
 func (g *Galleries) galleryByID(w http.ResponseWriter, r *http.Request) (*models.Gallery, error) {
 	vars := mux.Vars(r)
-	idStr := vars["id"]
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		http.Error(w, "Invalid gallery ID", http.StatusNotFound)
-		return nil, err
+	var gallery *models.Gallery
+	var err error
+	if slug, ok := vars["slug"]; ok {
+		gallery, err = g.galleryBySlug(vars["username"], slug)
+	} else {
+		var id int
+		id, err = strconv.Atoi(vars["id"])
+		if err != nil {
+			http.Error(w, "Invalid gallery ID", http.StatusNotFound)
+			return nil, err
+		}
+		gallery, err = g.gs.ByID(uint(id))
 	}
-	gallery, err := g.gs.ByID(uint(id))
 	if err != nil {
 		switch err {
 		case models.ErrNotFound:
@@ -261,15 +412,10 @@ func (g *Galleries) galleryByID(w http.ResponseWriter, r *http.Request) (*models
 
 // POST /galleries/:id/images/:filename/delete
 func (g *Galleries) ImageDelete(w http.ResponseWriter, r *http.Request) {
-	gallery, err := g.galleryByID(w, r)
+	gallery, err := g.authorize(w, r, models.PermEdit)
 	if err != nil {
 		return
 	}
-	user := context.User(r.Context())
-	if gallery.UserID != user.ID {
-		http.Error(w, "You do not have permission to edit this gallery or image", http.StatusForbidden)
-		return
-	}
 	// Get the filename from the path
 	filename := mux.Vars(r)["filename"]
 	// Build the Image model
@@ -282,17 +428,56 @@ func (g *Galleries) ImageDelete(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Render the edit page with any errors.
 		var vd views.Data
-		vd.Yield = gallery
+		vd.Yield = g.editPage(r, gallery)
 		vd.SetAlert(err)
 		g.EditView.Render(w, r, vd)
 		return
 	}
 	// If all goes well, redirect to the edit gallery page.
-	url, err := g.r.Get(EditGallery).URL("id", fmt.Sprintf("%v", gallery.ID))
+	url, err := g.galleryURL(gallery, EditGallery)
 	if err != nil {
 		http.Redirect(w, r, "/galleries", http.StatusFound)
 		return
 	}
 	http.Redirect(w, r, url.Path, http.StatusFound)
 
-}
\ No newline at end of file
+}
+
+// POST /galleries/:id/images/:filename/caption
+func (g *Galleries) ImageCaption(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermEdit)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	vd.Yield = g.editPage(r, gallery)
+	var form CaptionForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+	filename := mux.Vars(r)["filename"]
+	var img *models.Image
+	for i := range gallery.Images {
+		if gallery.Images[i].Filename == filename {
+			img = &gallery.Images[i]
+		}
+	}
+	if img == nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	img.Caption = strings.TrimSpace(form.Caption)
+	if err := g.is.Update(img); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+	url, err := g.galleryURL(gallery, EditGallery)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, url.Path, http.StatusFound)
+}
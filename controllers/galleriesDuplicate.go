@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"lenslocked.com/jobs"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// Galleries with more images than this have them copied in the background when they are duplicated, rather than
+// making the owner wait for the copy to finish.
+const backgroundCopyImages = 50
+
+// DuplicateForm asks whether the images should be copied along with the gallery's settings.
+type DuplicateForm struct {
+	Images bool `schema:"images"`
+}
+
+// Duplicate creates a new gallery with the same settings and tags as an existing one, and optionally the same images.
+//
+// POST /galleries/:id/duplicate
+func (g *Galleries) Duplicate(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	vd.Yield = g.editPage(r, gallery)
+	var form DuplicateForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+	duplicate := gallery.Duplicate()
+	if err := g.gs.Create(&duplicate); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+
+	alert := views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "This is your copy of " + gallery.Title + ".",
+	}
+	if form.Images {
+		copyImages := func() error {
+			return g.is.Copy(gallery.ID, duplicate.ID)
+		}
+		if len(gallery.Images) > backgroundCopyImages {
+			jobs.Go(fmt.Sprintf("copy images of gallery %d", gallery.ID), copyImages)
+			alert.Message += " Its images are being copied and will appear shortly."
+		} else if err := copyImages(); err != nil {
+			log.Println(err)
+			alert = views.Alert{
+				Level:   views.AlertLvlWarning,
+				Message: "The gallery was copied, but some of its images couldn't be. Please upload them again.",
+			}
+		}
+	}
+	g.redirectToEdit(w, r, &duplicate, alert)
+}
+
+// redirectToEdit sends the user to the gallery's edit page with the given alert.
+func (g *Galleries) redirectToEdit(w http.ResponseWriter, r *http.Request, gallery *models.Gallery, alert views.Alert) {
+	url, err := g.galleryURL(gallery, EditGallery)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// embedHTMLTmpl is the iframe OEmbed tells consumers to use
+const embedHTMLTmpl = `<iframe src="%s" width="%d" height="%d" title="%s" frameborder="0" loading="lazy"></iframe>`
+
+// The size of the iframe OEmbed suggests, unless the consumer asks for something smaller
+const (
+	embedWidth  = 600
+	embedHeight = 400
+)
+
+// embedPage is what the galleries/embed template expects.
+type embedPage struct {
+	*models.Gallery
+	// URL is the full address of the gallery's own page, which links in the embed open
+	URL string
+}
+
+// embedImage is an image in an embedded gallery.
+type embedImage struct {
+	*models.Image
+	URL string
+}
+
+// ImageData returns what the embed's galleryImage template expects for an image.
+func (p embedPage) ImageData(img models.Image) embedImage {
+	return embedImage{Image: &img, URL: p.URL}
+}
+
+// oEmbedResponse is a "rich" oEmbed response, see https://oembed.com.
+type oEmbedResponse struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name,omitempty"`
+	AuthorURL       string `json:"author_url,omitempty"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+}
+
+// embeddable reports whether a gallery may be shown on other sites. Only galleries anyone with the link can see are,
+// and not password protected ones, since there is no way to unlock them from inside an embed.
+func embeddable(gallery *models.Gallery) bool {
+	return gallery.EffectiveVisibility() != models.VisibilityPrivate && !gallery.HasPassword()
+}
+
+// Embed shows a gallery on its own, without the site's navigation, so that other sites can put it in an iframe.
+//
+// GET /embed/galleries/:id
+func (g *Galleries) Embed(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.galleryByID(w, r)
+	if err != nil {
+		// galleryByID handles errors
+		return
+	}
+	if !embeddable(gallery) {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return
+	}
+	showURL, err := g.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	g.recordStat(r, gallery, "", g.sts.RecordView)
+	// Let any site put the embed in a frame
+	w.Header().Set("Content-Security-Policy", "frame-ancestors *")
+	var vd views.Data
+	vd.Yield = embedPage{
+		Gallery: gallery,
+		URL:     absoluteURL(r, showURL.Path),
+	}
+	g.EmbedView.Render(w, r, vd)
+}
+
+// OEmbed tells sites that support oEmbed how to embed the public gallery at the url parameter. Only JSON responses are
+// supported, and maxwidth and maxheight shrink the suggested iframe.
+//
+// GET /oembed
+func (g *Galleries) OEmbed(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if format := params.Get("format"); format != "" && format != "json" {
+		http.Error(w, "Only the json format is supported", http.StatusNotImplemented)
+		return
+	}
+	gallery, err := g.galleryAt(r, params.Get("url"))
+	if err != nil || !gallery.IsPublic() || gallery.HasPassword() {
+		http.NotFound(w, r)
+		return
+	}
+	embedURL := absoluteURL(r, fmt.Sprintf("/embed/galleries/%d", gallery.ID))
+	width := maxSize(embedWidth, params.Get("maxwidth"))
+	height := maxSize(embedHeight, params.Get("maxheight"))
+	resp := oEmbedResponse{
+		Version:      "1.0",
+		Type:         "rich",
+		ProviderName: "LensLocked.com",
+		ProviderURL:  absoluteURL(r, "/"),
+		Title:        gallery.Title,
+		HTML: fmt.Sprintf(embedHTMLTmpl, template.HTMLEscapeString(embedURL), width, height,
+			template.HTMLEscapeString(gallery.Title)),
+		Width:  width,
+		Height: height,
+	}
+	if owner, err := g.us.ByID(gallery.UserID); err == nil {
+		resp.AuthorName = owner.Name
+		if owner.Username != "" {
+			if profileURL, err := g.r.Get(ShowProfile).URL("username", owner.Username); err == nil {
+				resp.AuthorURL = absoluteURL(r, profileURL.Path)
+			}
+		}
+	}
+	if images, err := g.is.ByGalleryID(gallery.ID); err == nil && len(images) > 0 {
+		cover := images[0]
+		resp.ThumbnailURL = imageURL(r, &cover)
+		resp.ThumbnailWidth = cover.Width
+		resp.ThumbnailHeight = cover.Height
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// galleryAt returns the gallery whose page is at the given URL on this site.
+func (g *Galleries) galleryAt(r *http.Request, rawURL string) (*models.Gallery, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != r.Host {
+		return nil, models.ErrNotFound
+	}
+	req, err := http.NewRequest(http.MethodGet, u.Path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var match mux.RouteMatch
+	if !g.r.Match(req, &match) {
+		return nil, models.ErrNotFound
+	}
+	switch match.Route.GetName() {
+	case ShowGallery:
+		return g.galleryBySlug(match.Vars["username"], match.Vars["slug"])
+	case ShowGalleryByID:
+		id, err := strconv.Atoi(match.Vars["id"])
+		if err != nil {
+			return nil, err
+		}
+		return g.gs.ByID(uint(id))
+	}
+	return nil, models.ErrNotFound
+}
+
+// maxSize returns size, or the limit the consumer asked for if that is smaller.
+func maxSize(size int, limit string) int {
+	if n, err := strconv.Atoi(limit); err == nil && n > 0 && n < size {
+		return n
+	}
+	return size
+}
@@ -0,0 +1,378 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	UploadAsGuest = "upload_as_guest"
+	// guestFormOverhead is how much larger than its photos a guest upload request can be, for the guest's name and
+	// the multipart encoding
+	guestFormOverhead = 1 << 20 // 1 megabyte
+	// maxGuestFiles caps how many photos a guest can send at once when their link has no limit
+	maxGuestFiles = 50
+)
+
+type GuestLinkForm struct {
+	Label      string `schema:"label"`
+	MaxUploads int    `schema:"max_uploads"`
+	MaxSizeMB  int    `schema:"max_size_mb"`
+}
+
+// guestLinksPage is what the galleries/guests template expects.
+type guestLinksPage struct {
+	*models.Gallery
+	Links   []models.GuestLink
+	Pending []models.GuestUpload
+	// NewLinkURL is only set right after a link is created, since that is the only time we know its token.
+	NewLinkURL string
+}
+
+// guestUploadPage is what the galleries/guest_upload template expects.
+type guestUploadPage struct {
+	Title     string
+	Token     string
+	Remaining int
+	MaxSizeMB int
+	GuestName string
+}
+
+func (g *Galleries) renderGuestLinks(w http.ResponseWriter, r *http.Request, vd views.Data, gallery *models.Gallery,
+	newLinkURL string) {
+	links, err := g.guests.LinksByGalleryID(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	pending, err := g.guests.Pending(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = guestLinksPage{
+		Gallery:    gallery,
+		Links:      links,
+		Pending:    pending,
+		NewLinkURL: newLinkURL,
+	}
+	g.GuestLinksView.Render(w, r, vd)
+}
+
+// GuestLinks lists a gallery's guest upload links, and the photos guests have uploaded that are waiting to be
+// approved.
+//
+// GET /galleries/:id/guests
+func (g *Galleries) GuestLinks(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	g.renderGuestLinks(w, r, views.Data{}, gallery, "")
+}
+
+// CreateGuestLink creates a new guest upload link and shows its URL to the owner once.
+//
+// POST /galleries/:id/guests
+func (g *Galleries) CreateGuestLink(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form GuestLinkForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderGuestLinks(w, r, vd, gallery, "")
+		return
+	}
+	link := models.GuestLink{
+		GalleryID:  gallery.ID,
+		Label:      form.Label,
+		MaxUploads: form.MaxUploads,
+		MaxSizeMB:  form.MaxSizeMB,
+	}
+	if err := g.guests.CreateLink(&link); err != nil {
+		vd.SetAlert(err)
+		g.renderGuestLinks(w, r, vd, gallery, "")
+		return
+	}
+	url, err := g.r.Get(UploadAsGuest).URL("token", link.Token)
+	if err != nil {
+		vd.SetAlert(err)
+		g.renderGuestLinks(w, r, vd, gallery, "")
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Upload link created. Copy it now - for security reasons we can't show it to you again.",
+	}
+	g.renderGuestLinks(w, r, vd, gallery, absoluteURL(r, url.Path))
+}
+
+// RevokeGuestLink stops a guest upload link from taking any more photos. Photos already uploaded with it stay in the
+// queue.
+//
+// POST /galleries/:id/guests/:lid/revoke
+func (g *Galleries) RevokeGuestLink(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	lid, err := strconv.Atoi(mux.Vars(r)["lid"])
+	if err != nil {
+		http.Error(w, "Upload link not found", http.StatusNotFound)
+		return
+	}
+	link, err := g.guests.LinkByID(uint(lid))
+	if err != nil || link.GalleryID != gallery.ID {
+		http.Error(w, "Upload link not found", http.StatusNotFound)
+		return
+	}
+	var vd views.Data
+	if err := g.guests.RevokeLink(link); err != nil {
+		vd.SetAlert(err)
+	} else {
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "Upload link revoked.",
+		}
+	}
+	g.renderGuestLinks(w, r, vd, gallery, "")
+}
+
+// guestUpload looks up the pending upload in the URL, making sure it belongs to the gallery.
+func (g *Galleries) guestUpload(w http.ResponseWriter, r *http.Request,
+	gallery *models.Gallery) (*models.GuestUpload, error) {
+	uid, err := strconv.Atoi(mux.Vars(r)["uid"])
+	if err != nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return nil, err
+	}
+	upload, err := g.guests.UploadByID(uint(uid))
+	if err == nil && upload.GalleryID != gallery.ID {
+		err = models.ErrNotFound
+	}
+	if err != nil {
+		http.Error(w, "Photo not found", http.StatusNotFound)
+		return nil, err
+	}
+	return upload, nil
+}
+
+// GuestUploadImage shows the owner a photo that is waiting to be approved.
+//
+// GET /galleries/:id/guests/uploads/:uid
+func (g *Galleries) GuestUploadImage(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	upload, err := g.guestUpload(w, r, gallery)
+	if err != nil {
+		return
+	}
+	// Guests choose what they upload, so don't let browsers treat it as anything but an image
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	serveRegularFile(w, r, upload.RelativePath(), nil)
+}
+
+// ApproveGuestUpload adds a photo a guest uploaded to the gallery.
+//
+// POST /galleries/:id/guests/uploads/:uid/approve
+func (g *Galleries) ApproveGuestUpload(w http.ResponseWriter, r *http.Request) {
+	g.moderateGuestUpload(w, r, true)
+}
+
+// RejectGuestUpload deletes a photo a guest uploaded.
+//
+// POST /galleries/:id/guests/uploads/:uid/reject
+func (g *Galleries) RejectGuestUpload(w http.ResponseWriter, r *http.Request) {
+	g.moderateGuestUpload(w, r, false)
+}
+
+func (g *Galleries) moderateGuestUpload(w http.ResponseWriter, r *http.Request, approve bool) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	upload, err := g.guestUpload(w, r, gallery)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	if approve {
+		err = g.guests.Approve(upload)
+	} else {
+		err = g.guests.Reject(upload)
+	}
+	if err != nil {
+		vd.SetAlert(err)
+		g.renderGuestLinks(w, r, vd, gallery, "")
+		return
+	}
+	g.redirectToGuestLinks(w, r, gallery, "")
+}
+
+// ApproveAllGuestUploads adds every photo waiting to be approved to the gallery.
+//
+// POST /galleries/:id/guests/uploads/approve
+func (g *Galleries) ApproveAllGuestUploads(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	pending, err := g.guests.Pending(gallery.ID)
+	if err != nil {
+		vd.SetAlert(err)
+		g.renderGuestLinks(w, r, vd, gallery, "")
+		return
+	}
+	for i := range pending {
+		if err := g.guests.Approve(&pending[i]); err != nil {
+			vd.SetAlert(err)
+			g.renderGuestLinks(w, r, vd, gallery, "")
+			return
+		}
+	}
+	g.redirectToGuestLinks(w, r, gallery, fmt.Sprintf("%d photo(s) added to the gallery.", len(pending)))
+}
+
+func (g *Galleries) redirectToGuestLinks(w http.ResponseWriter, r *http.Request, gallery *models.Gallery,
+	message string) {
+	path := fmt.Sprintf("/galleries/%d/guests", gallery.ID)
+	if message == "" {
+		http.Redirect(w, r, path, http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, path, http.StatusFound, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: message,
+	})
+}
+
+// guestLink looks up the guest upload link in the URL, and the gallery it uploads to. Visitors get the same answer
+// for revoked links as for ones that never existed.
+func (g *Galleries) guestLink(w http.ResponseWriter, r *http.Request) (*models.GuestLink, *models.Gallery, error) {
+	link, err := g.guests.LinkByToken(mux.Vars(r)["token"])
+	if err == nil && link.Revoked() {
+		err = models.ErrGuestLinkInvalid
+	}
+	var gallery *models.Gallery
+	if err == nil {
+		gallery, err = g.gs.ByID(link.GalleryID)
+	}
+	if err != nil {
+		switch err {
+		case models.ErrNotFound, models.ErrGuestLinkInvalid:
+			http.Error(w, "This upload link has been revoked or is no longer valid.", http.StatusGone)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return nil, nil, err
+	}
+	return link, gallery, nil
+}
+
+func (g *Galleries) renderGuestUpload(w http.ResponseWriter, r *http.Request, vd views.Data, link *models.GuestLink,
+	gallery *models.Gallery, guestName string) {
+	// Upload links are private, so keep them out of search engines
+	vd.Meta = &views.Meta{Title: "Add photos to " + gallery.Title, NoIndex: true}
+	vd.Yield = guestUploadPage{
+		Title:     gallery.Title,
+		Token:     mux.Vars(r)["token"],
+		Remaining: link.Remaining(),
+		MaxSizeMB: link.MaxSizeMB,
+		GuestName: guestName,
+	}
+	g.GuestView.Render(w, r, vd)
+}
+
+// GuestUploadForm lets anyone with a guest upload link add photos to the gallery, without signing in.
+//
+// GET /g/:token
+func (g *Galleries) GuestUploadForm(w http.ResponseWriter, r *http.Request) {
+	link, gallery, err := g.guestLink(w, r)
+	if err != nil {
+		return
+	}
+	g.renderGuestUpload(w, r, views.Data{}, link, gallery, "")
+}
+
+// GuestUpload puts the photos a guest uploaded in the gallery's moderation queue. Photos are taken one at a time, so
+// if one of them is over the link's limits the ones before it are kept.
+//
+// POST /g/:token
+func (g *Galleries) GuestUpload(w http.ResponseWriter, r *http.Request) {
+	link, gallery, err := g.guestLink(w, r)
+	if err != nil {
+		return
+	}
+	files := link.Remaining()
+	if files < 0 || files > maxGuestFiles {
+		files = maxGuestFiles
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, int64(files)*link.MaxBytes()+guestFormOverhead)
+	var vd views.Data
+	if err := r.ParseMultipartForm(maxMultipartMem); err != nil {
+		vd.AlertError(fmt.Sprintf("Please send at most %d photos of up to %d MB each.", files, link.MaxSizeMB))
+		g.renderGuestUpload(w, r, vd, link, gallery, "")
+		return
+	}
+	defer func() {
+		if err := r.MultipartForm.RemoveAll(); err != nil {
+			log.Println(err)
+		}
+	}()
+	guestName := r.PostFormValue("name")
+	headers := r.MultipartForm.File["images"]
+	if len(headers) == 0 {
+		vd.AlertError("Please choose some photos to upload.")
+		g.renderGuestUpload(w, r, vd, link, gallery, guestName)
+		return
+	}
+	uploaded, err := g.uploadAsGuest(link, guestName, headers)
+	if err != nil {
+		vd.SetAlert(err)
+		if uploaded > 0 {
+			vd.Alert.Message = fmt.Sprintf("%d photo(s) uploaded, but then: %s", uploaded, vd.Alert.Message)
+		}
+		g.renderGuestUpload(w, r, vd, link, gallery, guestName)
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level: views.AlertLvlSuccess,
+		Message: fmt.Sprintf("Thank you! Your %d photo(s) will appear in the gallery once they have been approved.",
+			uploaded),
+	}
+	g.renderGuestUpload(w, r, vd, link, gallery, guestName)
+}
+
+// uploadAsGuest uploads photos with a guest link until one of them fails, and returns how many made it.
+func (g *Galleries) uploadAsGuest(link *models.GuestLink, guestName string,
+	headers []*multipart.FileHeader) (int, error) {
+	for i, header := range headers {
+		if header.Size > link.MaxBytes() {
+			return i, models.ErrGuestImageTooBig
+		}
+		file, err := header.Open()
+		if err != nil {
+			return i, err
+		}
+		_, err = g.guests.Upload(link, guestName, file, header.Filename)
+		file.Close()
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(headers), nil
+}
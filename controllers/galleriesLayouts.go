@@ -0,0 +1,15 @@
+package controllers
+
+import "lenslocked.com/models"
+
+// galleryImage is an image on the gallery page, along with the page it is on. Every layout shows the same things
+// under each image (its caption, like button, heart button and comments), which need both.
+type galleryImage struct {
+	*models.Image
+	Page galleryPage
+}
+
+// ImageData returns what the galleryImage template expects for an image.
+func (p galleryPage) ImageData(img models.Image) galleryImage {
+	return galleryImage{Image: &img, Page: p}
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// likeButton is what the likeButton template expects.
+type likeButton struct {
+	Action  string
+	Count   int
+	Liked   bool
+	CanLike bool
+}
+
+// LikeData returns what a template needs to render the like button for the image with the given filename, or for the
+// gallery itself for an empty filename.
+func (p galleryPage) LikeData(filename string) likeButton {
+	action := fmt.Sprintf("/galleries/%d/like", p.ID)
+	if filename != "" {
+		action = fmt.Sprintf("/galleries/%d/images/%s/like", p.ID, url.PathEscape(filename))
+	}
+	return likeButton{
+		Action:  action,
+		Count:   p.likeCounts[filename],
+		Liked:   p.liked[filename],
+		CanLike: p.CanLike,
+	}
+}
+
+// setLikes fills in the gallery page's like counts, and which of them are the current user's. Only public galleries
+// can be liked, and only by users other than the owner.
+func (g *Galleries) setLikes(r *http.Request, page *galleryPage) {
+	if !page.IsPublic() {
+		return
+	}
+	counts, err := g.likes.Counts(page.ID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	page.likeCounts = counts
+	user := context.User(r.Context())
+	if user == nil || user.ID == page.UserID {
+		return
+	}
+	likes, err := g.likes.ByUserAndGallery(user.ID, page.ID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	page.CanLike = true
+	page.liked = make(map[string]bool, len(likes))
+	for _, like := range likes {
+		page.liked[like.Filename] = true
+	}
+}
+
+// LikeGallery likes a gallery, or unlikes it if the user already likes it.
+//
+// POST /galleries/:id/like
+func (g *Galleries) LikeGallery(w http.ResponseWriter, r *http.Request) {
+	g.toggleLike(w, r, "")
+}
+
+// LikeImage likes an image, or unlikes it if the user already likes it.
+//
+// POST /galleries/:id/images/:filename/like
+func (g *Galleries) LikeImage(w http.ResponseWriter, r *http.Request) {
+	g.toggleLike(w, r, mux.Vars(r)["filename"])
+}
+
+func (g *Galleries) toggleLike(w http.ResponseWriter, r *http.Request, filename string) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	user := context.User(r.Context())
+	if !gallery.IsPublic() || user.ID == gallery.UserID {
+		http.Error(w, "Only other photographers' public galleries can be liked", http.StatusForbidden)
+		return
+	}
+	if filename != "" && !hasImage(gallery, filename) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	if _, err := g.likes.Toggle(user.ID, gallery.ID, filename); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	showURL, err := g.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, showURL.Path, http.StatusFound)
+}
+
+// favouriteImage is a liked image, along with the gallery it is in.
+type favouriteImage struct {
+	models.Image
+	Gallery *models.Gallery
+}
+
+// Favourites lists every gallery and image the current user has liked. Anything that has since been deleted or
+// stopped being public is left out, but the like is kept in case it comes back.
+//
+// GET /favourites
+func (g *Galleries) Favourites(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	likes, err := g.likes.ByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	var page struct {
+		Galleries []models.Gallery
+		Images    []favouriteImage
+	}
+	galleries := make(map[uint]*models.Gallery)
+	for _, like := range likes {
+		gallery, ok := galleries[like.GalleryID]
+		if !ok {
+			gallery, err = g.gs.ByID(like.GalleryID)
+			if err == nil {
+				gallery.Images, err = g.is.ByGalleryID(gallery.ID)
+			}
+			if err != nil && err != models.ErrNotFound {
+				http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+				return
+			}
+			galleries[like.GalleryID] = gallery
+		}
+		if gallery == nil || !gallery.IsPublic() {
+			continue
+		}
+		if !like.OnImage() {
+			page.Galleries = append(page.Galleries, *gallery)
+			continue
+		}
+		if img := findImage(gallery, like.Filename); img != nil {
+			page.Images = append(page.Images, favouriteImage{*img, gallery})
+		}
+	}
+	var vd views.Data
+	vd.Yield = page
+	g.FavouritesView.Render(w, r, vd)
+}
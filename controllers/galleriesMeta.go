@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+
+	"lenslocked.com/markdown"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// galleryMeta describes a gallery page for search engines and link previews. Only public galleries are described in
+// full; other galleries just get a page title, and search engines are asked to leave them out.
+func (g *Galleries) galleryMeta(r *http.Request, gallery *models.Gallery) *views.Meta {
+	meta := views.Meta{Title: gallery.Title}
+	if !gallery.IsPublic() || gallery.HasPassword() {
+		meta.NoIndex = true
+		return &meta
+	}
+	meta.Description = views.Summarize(markdown.PlainText(gallery.Description))
+	if cover := gallery.Cover(); cover != nil {
+		meta.Image = imageURL(r, cover)
+	}
+	if showURL, err := g.galleryURL(gallery, ShowGallery); err == nil {
+		meta.URL = absoluteURL(r, showURL.Path)
+		meta.Links = append(meta.Links, views.MetaLink{
+			Rel:   "alternate",
+			Type:  "application/json+oembed",
+			Href:  absoluteURL(r, "/oembed") + "?url=" + url.QueryEscape(meta.URL),
+			Title: gallery.Title,
+		})
+	}
+	if owner, err := g.us.ByID(gallery.UserID); err == nil {
+		if link, ok := feedLink(g.r, r, owner); ok {
+			meta.Links = append(meta.Links, link)
+		}
+	}
+	return &meta
+}
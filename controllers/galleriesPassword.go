@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	// A visitor gets maxUnlockAttempts wrong guesses per gallery every unlockAttemptWindow.
+	maxUnlockAttempts   = 5
+	unlockAttemptWindow = 15 * time.Minute
+	unlockCookieMaxAge  = 30 * 24 * 60 * 60 // 30 days in seconds
+)
+
+type GalleryPasswordForm struct {
+	Password string `schema:"password"`
+	Remove   bool   `schema:"remove"`
+}
+
+// unlockCookie returns the name of the cookie that proves a visitor unlocked the given gallery.
+func unlockCookie(galleryID uint) string {
+	return fmt.Sprintf("gallery_%d", galleryID)
+}
+
+// Unlock checks the password for a protected gallery and, if it is correct, gives the visitor a signed cookie for it.
+//
+// POST /galleries/:id/unlock
+func (g *Galleries) Unlock(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.galleryByID(w, r)
+	if err != nil {
+		return
+	}
+	if !gallery.HasPassword() {
+		// Only tell visitors who could see the gallery anyway that it has no password, so that this doesn't give
+		// away which private gallery IDs exist
+		if !g.canView(r, gallery) {
+			http.Error(w, "Gallery not found", http.StatusNotFound)
+			return
+		}
+		g.redirectToShow(w, r, gallery, views.Alert{
+			Level:   views.AlertLvlInfo,
+			Message: "This gallery doesn't need a password.",
+		})
+		return
+	}
+
+	var vd views.Data
+	vd.Yield = gallery
+	key := fmt.Sprintf("%d:%s", gallery.ID, clientIP(r))
+	if g.unlockLimiter.Exceeded(key) {
+		vd.AlertError("Too many incorrect passwords. Please wait a few minutes and try again.")
+		g.UnlockView.Render(w, r, vd)
+		return
+	}
+	var form GalleryPasswordForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.UnlockView.Render(w, r, vd)
+		return
+	}
+	token, err := g.gs.Unlock(gallery, form.Password)
+	if err != nil {
+		if err == models.ErrPasswordIncorrect {
+			g.unlockLimiter.Hit(key)
+		}
+		vd.SetAlert(err)
+		g.UnlockView.Render(w, r, vd)
+		return
+	}
+	g.unlockLimiter.Reset(key)
+
+	// The cookie lets whoever holds it into the gallery for a month, so keep it off other sites' requests and, where
+	// we can, off plain HTTP
+	cookie := http.Cookie{
+		Name:     unlockCookie(gallery.ID),
+		Value:    token,
+		Path:     "/",
+		MaxAge:   unlockCookieMaxAge,
+		HttpOnly: true,
+		Secure:   overTLS(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, &cookie)
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Gallery unlocked. Enjoy!",
+	})
+}
+
+// SetPassword sets, changes or removes the password protecting a gallery.
+//
+// POST /galleries/:id/password
+func (g *Galleries) SetPassword(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+
+	var vd views.Data
+	vd.Yield = g.editPage(r, gallery)
+	var form GalleryPasswordForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+	msg := "Gallery password updated. Visitors who unlocked the old password will need the new one."
+	switch {
+	case form.Remove:
+		gallery.PasswordHash = ""
+		msg = "Gallery password removed."
+	case form.Password == "":
+		vd.SetAlert(models.ErrPasswordRequired)
+		g.EditView.Render(w, r, vd)
+		return
+	default:
+		gallery.Password = form.Password
+	}
+	if err := g.gs.Update(gallery); err != nil {
+		vd.SetAlert(err)
+		g.EditView.Render(w, r, vd)
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: msg,
+	}
+	g.EditView.Render(w, r, vd)
+}
@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	// A visitor can start maxSelections selections, across every gallery, every selectionWindow.
+	maxSelections   = 5
+	selectionWindow = time.Hour
+)
+
+// galleryPage is what the galleries/show template expects. Embedding the gallery keeps fields like .Title and
+// methods like .ImagesSplitN available to the template.
+type galleryPage struct {
+	*models.Gallery
+	Selection   *models.Selection
+	Breadcrumbs []models.Collection
+	Comments    []models.Comment
+	CanModerate bool
+	CanLike     bool
+	likeCounts  map[string]int
+	liked       map[string]bool
+}
+
+func (g *Galleries) showPage(r *http.Request, gallery *models.Gallery) galleryPage {
+	page := galleryPage{
+		Gallery:     gallery,
+		Selection:   g.selection(r, gallery),
+		Breadcrumbs: g.breadcrumbs(r, gallery),
+		Comments:    g.comments(r, gallery),
+		CanModerate: models.RoleAllows(g.role(r, gallery), models.PermManage),
+	}
+	g.setLikes(r, &page)
+	return page
+}
+
+// ProofingForm is used to start a selection and to submit it.
+type ProofingForm struct {
+	Name  string `schema:"name"`
+	Email string `schema:"email"`
+	Note  string `schema:"note"`
+}
+
+// selectionCookie returns the name of the cookie holding a client's selection token for the given gallery.
+// Each gallery gets its own cookie so a client can proof several galleries at once.
+func selectionCookie(galleryID uint) string {
+	return fmt.Sprintf("selection_%d", galleryID)
+}
+
+// setSelectionCookie remembers the selection with the given token in the visitor's browser.
+func setSelectionCookie(w http.ResponseWriter, galleryID uint, token string) {
+	cookie := http.Cookie{
+		Name:     selectionCookie(galleryID),
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+	}
+	http.SetCookie(w, &cookie)
+}
+
+// selection looks up the proofing selection the current visitor is working on, or returns nil if there is none.
+func (g *Galleries) selection(r *http.Request, gallery *models.Gallery) *models.Selection {
+	if !gallery.Proofing {
+		return nil
+	}
+	cookie, err := r.Cookie(selectionCookie(gallery.ID))
+	if err != nil {
+		return nil
+	}
+	selection, err := g.ss.ByToken(cookie.Value)
+	if err != nil || selection.GalleryID != gallery.ID {
+		return nil
+	}
+	images, err := g.ss.Images(selection.ID)
+	if err != nil {
+		return nil
+	}
+	selection.Images = images
+	return selection
+}
+
+// renderShow re-renders the gallery page with an alert for the given error.
+func (g *Galleries) renderShow(w http.ResponseWriter, r *http.Request, gallery *models.Gallery, err error) {
+	var vd views.Data
+	vd.Yield = g.showPage(r, gallery)
+	vd.SetAlert(err)
+	g.ShowView.Render(w, r, vd)
+}
+
+// redirectToShow sends the visitor back to the gallery page with the given alert.
+func (g *Galleries) redirectToShow(w http.ResponseWriter, r *http.Request, gallery *models.Gallery, alert views.Alert) {
+	url, err := g.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
+
+// StartProofing creates a selection for the visitor and remembers it with a cookie.
+//
+// POST /galleries/:id/proofing
+func (g *Galleries) StartProofing(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	if !gallery.Proofing {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return
+	}
+	var form ProofingForm
+	if err := parseForm(r, &form); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	if !g.proofLimiter.Allow(clientIP(r)) {
+		g.renderShow(w, r, gallery, models.ErrTooManySelections)
+		return
+	}
+	selection := models.Selection{
+		GalleryID: gallery.ID,
+		Name:      form.Name,
+		Email:     form.Email,
+	}
+	if err := g.ss.Create(&selection); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	setSelectionCookie(w, gallery.ID, selection.Token)
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Thanks " + selection.Name + "! Heart the images you would like and submit your selection when you are done.",
+	})
+}
+
+// ProofingLink picks up the selection a photographer started for their client, so that the client is identified by
+// the token in the link rather than by entering their name and email address. It works from any browser, so the same
+// link also lets a client carry on with their selection on another device.
+//
+// GET /galleries/:id/proofing/:token
+func (g *Galleries) ProofingLink(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	if !gallery.Proofing {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return
+	}
+	token := mux.Vars(r)["token"]
+	selection, err := g.ss.ByToken(token)
+	if err != nil || selection.GalleryID != gallery.ID {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+	setSelectionCookie(w, gallery.ID, token)
+	message := "Welcome " + selection.Name + "! Heart the images you would like and submit your selection when you " +
+		"are done."
+	if selection.Submitted() {
+		message = "Welcome back " + selection.Name + "! Your selection has already been sent to the photographer."
+	}
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: message,
+	})
+}
+
+// HeartImage adds an image to, or removes it from, the visitor's selection.
+//
+// POST /galleries/:id/proofing/images/:filename
+func (g *Galleries) HeartImage(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	selection := g.selection(r, gallery)
+	if selection == nil {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+	filename := mux.Vars(r)["filename"]
+	if !hasImage(gallery, filename) {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	if err := g.ss.Toggle(selection, filename); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	url, err := g.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, url.Path, http.StatusFound)
+}
+
+// SubmitSelection finalises the visitor's selection and emails the owner of the gallery.
+//
+// POST /galleries/:id/proofing/submit
+func (g *Galleries) SubmitSelection(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermView)
+	if err != nil {
+		return
+	}
+	selection := g.selection(r, gallery)
+	if selection == nil {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+	var form ProofingForm
+	if err := parseForm(r, &form); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+	selection.Note = form.Note
+	if err := g.ss.Submit(selection); err != nil {
+		g.renderShow(w, r, gallery, err)
+		return
+	}
+
+	// The selection is saved at this point, so a failed email shouldn't be reported to the client as an error. The
+	// owner can still find it on the selections page.
+	owner, err := g.us.ByID(gallery.UserID)
+	if err == nil {
+		err = g.emailer.SelectionSubmitted(owner.Email, gallery, selection)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+	g.redirectToShow(w, r, gallery, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Your selection has been sent to the photographer. Thank you!",
+	})
+}
+
+// Selections lists every proofing selection made on a gallery.
+//
+// GET /galleries/:id/selections
+func (g *Galleries) Selections(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	g.renderSelections(w, r, views.Data{}, gallery, "")
+}
+
+// selectionsPage is what the galleries/selections template expects. Link is the proofing link of a client who has
+// just been invited, which can only be shown once because only a hash of its token is stored.
+type selectionsPage struct {
+	*models.Gallery
+	Selections []models.Selection
+	Link       string
+}
+
+func (g *Galleries) renderSelections(w http.ResponseWriter, r *http.Request, vd views.Data,
+	gallery *models.Gallery, link string) {
+	selections, err := g.ss.ByGalleryID(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	for i := range selections {
+		images, err := g.ss.Images(selections[i].ID)
+		if err != nil {
+			http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+			return
+		}
+		selections[i].Images = images
+	}
+	vd.Yield = selectionsPage{
+		Gallery:    gallery,
+		Selections: selections,
+		Link:       link,
+	}
+	g.SelectionsView.Render(w, r, vd)
+}
+
+// InviteClient starts a selection on behalf of a client and shows the photographer a proofing link to send them.
+//
+// POST /galleries/:id/selections
+func (g *Galleries) InviteClient(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form ProofingForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderSelections(w, r, vd, gallery, "")
+		return
+	}
+	if !gallery.Proofing {
+		vd.AlertError("Turn on proofing for this gallery before inviting clients.")
+		g.renderSelections(w, r, vd, gallery, "")
+		return
+	}
+	selection := models.Selection{
+		GalleryID: gallery.ID,
+		Name:      form.Name,
+		Email:     form.Email,
+	}
+	if err := g.ss.Create(&selection); err != nil {
+		vd.SetAlert(err)
+		g.renderSelections(w, r, vd, gallery, "")
+		return
+	}
+	link := absoluteURL(r, fmt.Sprintf("/galleries/%d/proofing/%s", gallery.ID, selection.Token))
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Send " + selection.Name + " the link below so they can start proofing.",
+	}
+	g.renderSelections(w, r, vd, gallery, link)
+}
+
+// SelectionCSV exports the filenames in a selection so they can be imported into editing software.
+//
+// GET /galleries/:id/selections/:sid/csv
+func (g *Galleries) SelectionCSV(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	sid, err := strconv.Atoi(mux.Vars(r)["sid"])
+	if err != nil {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+	selection, err := g.ss.ByID(uint(sid))
+	if err != nil || selection.GalleryID != gallery.ID {
+		http.Error(w, "Selection not found", http.StatusNotFound)
+		return
+	}
+	images, err := g.ss.Images(selection.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"gallery-%d-selection-%d.csv\"", gallery.ID, selection.ID))
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"filename"})
+	for _, img := range images {
+		cw.Write([]string{img.Filename})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"lenslocked.com/models"
+)
+
+// expiryNotice is how long before a gallery expires its owner is emailed about it, if they asked to be.
+const expiryNotice = 7 * 24 * time.Hour
+
+// setSchedule copies the publish and expiry times from the form to the gallery. Times are entered in UTC. Changing
+// the expiry time means the owner should be reminded again before the new one.
+func setSchedule(gallery *models.Gallery, form GalleryForm) error {
+	publishAt, err := parseScheduleTime(form.PublishAt)
+	if err != nil {
+		return err
+	}
+	expireAt, err := parseScheduleTime(form.ExpireAt)
+	if err != nil {
+		return err
+	}
+	if !sameTime(gallery.ExpireAt, expireAt) || !form.NotifyExpiry {
+		gallery.ExpiryNotifiedAt = nil
+	}
+	gallery.PublishAt = publishAt
+	gallery.ExpireAt = expireAt
+	gallery.NotifyExpiry = form.NotifyExpiry
+	return nil
+}
+
+// parseScheduleTime parses a time entered in a form. An empty field means there is no time set.
+func parseScheduleTime(s string) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(models.ScheduleLayout, s, time.UTC)
+	if err != nil {
+		return nil, models.ErrScheduleInvalid
+	}
+	return &t, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// SendExpiryReminders emails the owners of galleries that expire within the next week, if they asked to be told. It
+// is run on a schedule, and each gallery is only ever reminded about once per expiry time.
+func (g *Galleries) SendExpiryReminders() error {
+	galleries, err := g.gs.ExpiringBefore(time.Now().Add(expiryNotice))
+	if err != nil {
+		return err
+	}
+	for i := range galleries {
+		gallery := &galleries[i]
+		owner, err := g.us.ByID(gallery.UserID)
+		if err != nil {
+			return err
+		}
+		if err := g.emailer.GalleryExpiring(owner.Email, gallery); err != nil {
+			// Try again next time rather than giving up on the other galleries
+			log.Println(err)
+			continue
+		}
+		if err := g.gs.MarkExpiryNotified(gallery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
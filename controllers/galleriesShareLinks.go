@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const shareLinkDateLayout = "2006-01-02"
+
+type ShareLinkForm struct {
+	Label     string `schema:"label"`
+	ExpiresOn string `schema:"expires_on"`
+	MaxViews  int    `schema:"max_views"`
+}
+
+// shareLinksPage is what the galleries/links template expects.
+type shareLinksPage struct {
+	*models.Gallery
+	Links []models.ShareLink
+	// NewLinkURL is only set right after a link is created, since that is the only time we know its token.
+	NewLinkURL string
+}
+
+// shareCookie returns the name of the cookie holding the share link token a visitor used to open the given gallery.
+func shareCookie(galleryID uint) string {
+	return fmt.Sprintf("share_%d", galleryID)
+}
+
+// sharedWith reports whether the visitor opened a share link for the gallery that is still valid.
+func (g *Galleries) sharedWith(r *http.Request, gallery *models.Gallery) bool {
+	cookie, err := r.Cookie(shareCookie(gallery.ID))
+	if err != nil {
+		return false
+	}
+	link, err := g.sls.ByToken(cookie.Value)
+	if err != nil {
+		return false
+	}
+	return link.GalleryID == gallery.ID && link.Valid()
+}
+
+func (g *Galleries) renderShareLinks(w http.ResponseWriter, r *http.Request, vd views.Data, gallery *models.Gallery,
+	newLinkURL string) {
+	links, err := g.sls.ByGalleryID(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = shareLinksPage{
+		Gallery:    gallery,
+		Links:      links,
+		NewLinkURL: newLinkURL,
+	}
+	g.ShareLinksView.Render(w, r, vd)
+}
+
+// ShareLinks lists a gallery's share links.
+//
+// GET /galleries/:id/links
+func (g *Galleries) ShareLinks(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	g.renderShareLinks(w, r, views.Data{}, gallery, "")
+}
+
+// CreateShareLink creates a new share link and shows its URL to the owner once.
+//
+// POST /galleries/:id/links
+func (g *Galleries) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form ShareLinkForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderShareLinks(w, r, vd, gallery, "")
+		return
+	}
+	link := models.ShareLink{
+		GalleryID: gallery.ID,
+		Label:     form.Label,
+		MaxViews:  form.MaxViews,
+	}
+	if form.ExpiresOn != "" {
+		day, err := time.ParseInLocation(shareLinkDateLayout, form.ExpiresOn, time.Local)
+		if err != nil {
+			vd.AlertError("Please provide the expiry date as YYYY-MM-DD.")
+			g.renderShareLinks(w, r, vd, gallery, "")
+			return
+		}
+		// Links stay valid until the end of the day they expire on
+		expiresAt := day.AddDate(0, 0, 1)
+		link.ExpiresAt = &expiresAt
+	}
+	if err := g.sls.Create(&link); err != nil {
+		vd.SetAlert(err)
+		g.renderShareLinks(w, r, vd, gallery, "")
+		return
+	}
+	url, err := g.r.Get(VisitShareLink).URL("token", link.Token)
+	if err != nil {
+		vd.SetAlert(err)
+		g.renderShareLinks(w, r, vd, gallery, "")
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Share link created. Copy it now - for security reasons we can't show it to you again.",
+	}
+	g.renderShareLinks(w, r, vd, gallery, absoluteURL(r, url.Path))
+}
+
+// RevokeShareLink stops a share link from granting access to the gallery.
+//
+// POST /galleries/:id/links/:lid/revoke
+func (g *Galleries) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	lid, err := strconv.Atoi(mux.Vars(r)["lid"])
+	if err != nil {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	link, err := g.sls.ByID(uint(lid))
+	if err != nil || link.GalleryID != gallery.ID {
+		http.Error(w, "Share link not found", http.StatusNotFound)
+		return
+	}
+	var vd views.Data
+	if err := g.sls.Revoke(link); err != nil {
+		vd.SetAlert(err)
+	} else {
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "Share link revoked.",
+		}
+	}
+	g.renderShareLinks(w, r, vd, gallery, "")
+}
+
+// VisitShareLink counts a view against a share link and lets the visitor into its gallery.
+//
+// GET /s/:token
+func (g *Galleries) VisitShareLink(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	link, err := g.sls.Visit(token)
+	if err != nil {
+		if err == models.ErrShareLinkInvalid {
+			http.Error(w, "This share link has expired or is no longer valid.", http.StatusGone)
+			return
+		}
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	cookie := http.Cookie{
+		Name:     shareCookie(link.GalleryID),
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+	}
+	if link.ExpiresAt != nil {
+		cookie.Expires = *link.ExpiresAt
+	}
+	http.SetCookie(w, &cookie)
+	gallery, err := g.gs.ByID(link.GalleryID)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	url, err := g.galleryURL(gallery, ShowGallery)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, url.Path, http.StatusFound)
+}
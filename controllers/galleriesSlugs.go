@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+)
+
+// galleryURL builds the URL of a gallery page. route is ShowGallery or EditGallery, which put the gallery's slug under
+// its owner's profile. Galleries whose owner hasn't picked a username yet keep their numeric URLs.
+func (g *Galleries) galleryURL(gallery *models.Gallery, route string) (*url.URL, error) {
+	if gallery.Slug != "" {
+		if owner, err := g.us.ByID(gallery.UserID); err == nil && owner.Username != "" {
+			return g.r.Get(route).URL("username", owner.Username, "slug", gallery.Slug)
+		}
+	}
+	byID := ShowGalleryByID
+	if route == EditGallery {
+		byID = EditGalleryByID
+	}
+	return g.r.Get(byID).URL("id", strconv.Itoa(int(gallery.ID)))
+}
+
+// galleryPath returns the path of a gallery's page, like galleryURL does, for lists of galleries whose owners'
+// usernames have already been looked up.
+func galleryPath(router *mux.Router, gallery *models.Gallery, username string) string {
+	route, pairs := ShowGalleryByID, []string{"id", strconv.Itoa(int(gallery.ID))}
+	if username != "" && gallery.Slug != "" {
+		route, pairs = ShowGallery, []string{"username", username, "slug", gallery.Slug}
+	}
+	u, err := router.Get(route).URL(pairs...)
+	if err != nil {
+		return "/"
+	}
+	return u.Path
+}
+
+// galleryBySlug looks up a gallery by its owner's username and its slug.
+func (g *Galleries) galleryBySlug(username, slug string) (*models.Gallery, error) {
+	owner, err := g.us.ByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return g.gs.BySlug(owner.ID, slug)
+}
+
+// redirectToSlug sends visitors who used a gallery's numeric URL on to its slug URL, if it has one, and reports
+// whether it did. Slugs can be changed, so the redirect is temporary to stop browsers remembering it.
+func (g *Galleries) redirectToSlug(w http.ResponseWriter, r *http.Request, gallery *models.Gallery,
+	route string) bool {
+	if _, ok := mux.Vars(r)["id"]; !ok {
+		return false
+	}
+	u, err := g.galleryURL(gallery, route)
+	if err != nil || u.Path == r.URL.Path {
+		return false
+	}
+	u.RawQuery = r.URL.RawQuery
+	http.Redirect(w, r, u.String(), http.StatusFound)
+	return true
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"lenslocked.com/models"
+)
+
+// statsDays is how many days of stats the owner sees on the edit page.
+const statsDays = 30
+
+// recordStat counts a visit with record, unless it was made by the owner or a collaborator: they are the ones reading
+// the stats, so their own visits would only get in the way. Nothing about the visitor is stored. A failure to count
+// is logged rather than shown, since the visitor has done nothing wrong.
+func (g *Galleries) recordStat(r *http.Request, gallery *models.Gallery, filename string,
+	record func(galleryID uint, filename string) error) {
+	if r.Method != http.MethodGet || g.role(r, gallery) != "" {
+		return
+	}
+	if err := record(gallery.ID, filename); err != nil {
+		log.Println(err)
+	}
+}
+
+// StatsCSV exports every day's views and downloads of the gallery and its images. The image column is empty for views
+// of the gallery page itself.
+//
+// GET /galleries/:id/stats.csv
+func (g *Galleries) StatsCSV(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	stats, err := g.sts.ByGalleryID(gallery.ID, time.Time{})
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=\"gallery-%d-stats.csv\"", gallery.ID))
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"date", "image", "views", "downloads"})
+	for _, stat := range stats {
+		cw.Write([]string{
+			stat.Day.Format("2006-01-02"),
+			stat.Filename,
+			strconv.Itoa(stat.Views),
+			strconv.Itoa(stat.Downloads),
+		})
+	}
+	cw.Flush()
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+type TransferForm struct {
+	Email string `schema:"email"`
+}
+
+// transferPage is what the galleries/transfer template expects.
+type transferPage struct {
+	*models.Gallery
+	Pending    *models.Transfer
+	AuditTrail []models.AuditEntry
+}
+
+// acceptTransferPage is what the galleries/accept template expects.
+type acceptTransferPage struct {
+	Gallery  *models.Gallery
+	Transfer *models.Transfer
+	FromName string
+	Token    string
+}
+
+func (g *Galleries) renderTransfer(w http.ResponseWriter, r *http.Request, vd views.Data, gallery *models.Gallery) {
+	pending, err := g.trs.PendingByGalleryID(gallery.ID)
+	if err != nil && err != models.ErrNotFound {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	trail, err := g.trs.AuditTrail(gallery.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = transferPage{
+		Gallery:    gallery,
+		Pending:    pending,
+		AuditTrail: trail,
+	}
+	g.TransferView.Render(w, r, vd)
+}
+
+// Transfer shows the form for handing a gallery over to another account, along with the gallery's audit trail.
+//
+// GET /galleries/:id/transfer
+func (g *Galleries) Transfer(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	g.renderTransfer(w, r, views.Data{}, gallery)
+}
+
+// RequestTransfer emails someone an offer to take over a gallery. Nothing changes hands until they accept it.
+//
+// POST /galleries/:id/transfer
+func (g *Galleries) RequestTransfer(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	var form TransferForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		g.renderTransfer(w, r, vd, gallery)
+		return
+	}
+	user := context.User(r.Context())
+	if strings.EqualFold(strings.TrimSpace(form.Email), user.Email) {
+		vd.SetAlert(models.ErrTransferToSelf)
+		g.renderTransfer(w, r, vd, gallery)
+		return
+	}
+	transfer := models.Transfer{
+		GalleryID:  gallery.ID,
+		FromUserID: user.ID,
+		ToEmail:    form.Email,
+	}
+	if err := g.trs.Create(&transfer); err != nil {
+		vd.SetAlert(err)
+		g.renderTransfer(w, r, vd, gallery)
+		return
+	}
+
+	url, err := g.r.Get(AcceptTransfer).URL("token", transfer.Token)
+	if err == nil {
+		err = g.emailer.TransferRequested(transfer.ToEmail, user.Name, gallery, &transfer, absoluteURL(r, url.Path))
+	}
+	if err != nil {
+		// Nobody will ever receive the offer. Cancel it so the owner can try again.
+		log.Println(err)
+		g.trs.Cancel(&transfer, user.ID)
+		vd.AlertError("We couldn't send the transfer email. Please try again.")
+		g.renderTransfer(w, r, vd, gallery)
+		return
+	}
+	vd.Alert = &views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Transfer offered to " + transfer.ToEmail + ". The gallery is yours until they accept it.",
+	}
+	g.renderTransfer(w, r, vd, gallery)
+}
+
+// CancelTransfer withdraws a transfer that hasn't been accepted yet.
+//
+// POST /galleries/:id/transfer/cancel
+func (g *Galleries) CancelTransfer(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermManage)
+	if err != nil {
+		return
+	}
+	var vd views.Data
+	transfer, err := g.trs.PendingByGalleryID(gallery.ID)
+	if err == nil {
+		err = g.trs.Cancel(transfer, context.User(r.Context()).ID)
+	}
+	if err != nil {
+		vd.SetAlert(err)
+	} else {
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "The transfer to " + transfer.ToEmail + " has been cancelled.",
+		}
+	}
+	g.renderTransfer(w, r, vd, gallery)
+}
+
+// pendingTransfer looks up the transfer in the URL, responding with a 404 if it can no longer be accepted.
+func (g *Galleries) pendingTransfer(w http.ResponseWriter, r *http.Request) (*models.Transfer, error) {
+	transfer, err := g.trs.ByToken(mux.Vars(r)["token"])
+	if err == nil && !transfer.Pending() {
+		err = models.ErrTokenInvalid
+	}
+	if err != nil {
+		http.Error(w, "This transfer is not valid. It may have expired, been cancelled or already been accepted.",
+			http.StatusNotFound)
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// ConfirmTransfer asks the recipient of a transfer whether they want to take the gallery over.
+//
+// GET /transfers/:token
+func (g *Galleries) ConfirmTransfer(w http.ResponseWriter, r *http.Request) {
+	transfer, err := g.pendingTransfer(w, r)
+	if err != nil {
+		return
+	}
+	gallery, err := g.gs.ByID(transfer.GalleryID)
+	if err != nil {
+		http.Error(w, "This gallery no longer exists.", http.StatusNotFound)
+		return
+	}
+	page := acceptTransferPage{
+		Gallery:  gallery,
+		Transfer: transfer,
+		Token:    mux.Vars(r)["token"],
+	}
+	if from, err := g.us.ByID(transfer.FromUserID); err == nil {
+		page.FromName = from.Name
+	}
+	var vd views.Data
+	if context.User(r.Context()).Email != transfer.ToEmail {
+		vd.SetAlert(models.ErrTransferWrongUser)
+	}
+	vd.Yield = page
+	g.AcceptView.Render(w, r, vd)
+}
+
+// AcceptTransfer makes the signed in user the owner of the gallery. The gallery's images, share links, comments and
+// proofing selections all belong to the gallery, so they come along with it.
+//
+// POST /transfers/:token
+func (g *Galleries) AcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	transfer, err := g.trs.Accept(mux.Vars(r)["token"], user)
+	if err != nil {
+		switch err {
+		case models.ErrTokenInvalid:
+			http.Error(w, "This transfer is not valid. It may have expired, been cancelled or already been accepted.",
+				http.StatusNotFound)
+		case models.ErrTransferWrongUser, models.ErrTransferToSelf:
+			var vd views.Data
+			vd.SetAlert(err)
+			views.RedirectAlert(w, r, r.URL.Path, http.StatusFound, *vd.Alert)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return
+	}
+	gallery, err := g.gs.ByID(transfer.GalleryID)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	url, err := g.galleryURL(gallery, EditGallery)
+	if err != nil {
+		http.Redirect(w, r, "/galleries", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Transfer accepted. This gallery is now yours.",
+	})
+}
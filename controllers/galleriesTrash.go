@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// trashedGallery is a gallery in the trash, along with when it will be deleted forever.
+type trashedGallery struct {
+	models.Gallery
+	PurgeAt time.Time
+}
+
+// Trash lists the current user's deleted galleries.
+//
+// GET /galleries/trash
+func (g *Galleries) Trash(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	galleries, err := g.gs.TrashedByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	trashed := make([]trashedGallery, len(galleries))
+	for i := range galleries {
+		trashed[i] = trashedGallery{galleries[i], g.gs.PurgeAt(&galleries[i])}
+	}
+	var vd views.Data
+	vd.Yield = trashed
+	g.TrashView.Render(w, r, vd)
+}
+
+// Restore takes a gallery out of the trash. If the collection it was in has been deleted in the meantime, the gallery
+// is restored outside of any collection.
+//
+// POST /galleries/:id/restore
+func (g *Galleries) Restore(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.trashedGallery(w, r)
+	if err != nil {
+		return
+	}
+	if err := g.gs.Restore(gallery.ID); err != nil {
+		g.redirectToTrash(w, r, views.Alert{Level: views.AlertLvlError, Message: views.AlertMsgGeneric})
+		return
+	}
+	g.redirectToTrash(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: gallery.Title + " has been restored.",
+	})
+}
+
+// DeleteForever deletes a gallery in the trash along with all of its images. There is no undoing this.
+//
+// POST /galleries/:id/destroy
+func (g *Galleries) DeleteForever(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.trashedGallery(w, r)
+	if err != nil {
+		return
+	}
+	if err := g.gs.DeleteForever(gallery.ID); err != nil {
+		g.redirectToTrash(w, r, views.Alert{Level: views.AlertLvlError, Message: views.AlertMsgGeneric})
+		return
+	}
+	g.redirectToTrash(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: gallery.Title + " has been deleted forever.",
+	})
+}
+
+// trashedGallery looks up a gallery in the current user's trash. Only the owner can see their trash, since
+// collaborators lose access to a gallery as soon as it is deleted.
+func (g *Galleries) trashedGallery(w http.ResponseWriter, r *http.Request) (*models.Gallery, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid gallery ID", http.StatusNotFound)
+		return nil, err
+	}
+	gallery, err := g.gs.TrashedByID(uint(id))
+	if err == nil && gallery.UserID != context.User(r.Context()).ID {
+		err = models.ErrNotFound
+	}
+	if err != nil {
+		http.Error(w, "Gallery not found", http.StatusNotFound)
+		return nil, err
+	}
+	return gallery, nil
+}
+
+// redirectToTrash sends the user back to their trash with the given alert.
+func (g *Galleries) redirectToTrash(w http.ResponseWriter, r *http.Request, alert views.Alert) {
+	url, err := g.r.Get(TrashGalleries).URL()
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// imageVersionsPage is what the galleries/versions template expects.
+type imageVersionsPage struct {
+	Gallery  *models.Gallery
+	Image    *models.Image
+	Versions []models.ImageVersion
+}
+
+// findImage returns the gallery's image with the given filename, or nil if it has none.
+func findImage(gallery *models.Gallery, filename string) *models.Image {
+	for i := range gallery.Images {
+		if gallery.Images[i].Filename == filename {
+			return &gallery.Images[i]
+		}
+	}
+	return nil
+}
+
+// imageURL is the full URL of an image, including its version so that caches elsewhere, like link previews and feed
+// readers, pick up a new version once the image has been replaced.
+func imageURL(r *http.Request, img *models.Image) string {
+	ret := absoluteURL(r, "/"+img.RelativePath())
+	if v := img.VersionQuery(); v != "" {
+		ret += "?" + v
+	}
+	return ret
+}
+
+// setImageCaching tells browsers how long they can keep an image. A URL with the image's current version in it always
+// gets the same file, so it can be kept for good; replacing the image changes its URL. Any other URL may show a
+// different version later, so browsers have to check with us before using their copy.
+func setImageCaching(w http.ResponseWriter, r *http.Request, gallery *models.Gallery, current int) {
+	if v := r.URL.Query().Get("v"); v == "" || v != strconv.Itoa(current) {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	scope := "private"
+	if gallery.IsPublic() {
+		scope = "public"
+	}
+	w.Header().Set("Cache-Control", scope+", max-age=31536000, immutable")
+}
+
+func (g *Galleries) renderImageVersions(w http.ResponseWriter, r *http.Request, vd views.Data,
+	gallery *models.Gallery, img *models.Image) {
+	versions, err := g.is.Versions(img)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	vd.Yield = imageVersionsPage{
+		Gallery:  gallery,
+		Image:    img,
+		Versions: versions,
+	}
+	g.VersionsView.Render(w, r, vd)
+}
+
+// imageFromURL looks up the image named in the URL among the gallery's images.
+func imageFromURL(w http.ResponseWriter, r *http.Request, gallery *models.Gallery) *models.Image {
+	img := findImage(gallery, mux.Vars(r)["filename"])
+	if img == nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+	}
+	return img
+}
+
+// ImageVersions shows an image's current version along with its earlier ones.
+//
+// GET /galleries/:id/images/:filename/versions
+func (g *Galleries) ImageVersions(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermEdit)
+	if err != nil {
+		return
+	}
+	img := imageFromURL(w, r, gallery)
+	if img == nil {
+		return
+	}
+	g.renderImageVersions(w, r, views.Data{}, gallery, img)
+}
+
+// ReplaceImage uploads a new version of an image. The image keeps its filename, so it stays in the same place in the
+// gallery along with its caption, comments and likes.
+//
+// POST /galleries/:id/images/:filename/replace
+func (g *Galleries) ReplaceImage(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermEdit)
+	if err != nil {
+		return
+	}
+	img := imageFromURL(w, r, gallery)
+	if img == nil {
+		return
+	}
+	var vd views.Data
+	if err := r.ParseMultipartForm(maxMultipartMem); err != nil {
+		vd.SetAlert(err)
+		g.renderImageVersions(w, r, vd, gallery, img)
+		return
+	}
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		vd.AlertError("Please choose the new version of the image to upload.")
+		g.renderImageVersions(w, r, vd, gallery, img)
+		return
+	}
+	defer file.Close()
+	if err := g.is.Replace(img, file); err != nil {
+		vd.SetAlert(err)
+		g.renderImageVersions(w, r, vd, gallery, img)
+		return
+	}
+	g.redirectToImageVersions(w, r, img, fmt.Sprintf("Image replaced. This is now version %d.", img.Version))
+}
+
+// ImageVersionServe serves an earlier version of an image, so that it can be compared with the current one before it
+// is restored.
+//
+// GET /galleries/:id/images/:filename/versions/:version
+func (g *Galleries) ImageVersionServe(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermEdit)
+	if err != nil {
+		return
+	}
+	version, err := g.imageVersion(w, r, gallery)
+	if err != nil {
+		return
+	}
+	serveRegularFile(w, r, version.RelativePath(), nil)
+}
+
+// RestoreImageVersion makes an earlier version of an image the current one again. The version it replaces is kept,
+// so restoring can be undone.
+//
+// POST /galleries/:id/images/:filename/versions/:version/restore
+func (g *Galleries) RestoreImageVersion(w http.ResponseWriter, r *http.Request) {
+	gallery, err := g.authorize(w, r, models.PermEdit)
+	if err != nil {
+		return
+	}
+	version, err := g.imageVersion(w, r, gallery)
+	if err != nil {
+		return
+	}
+	img := findImage(gallery, version.Filename)
+	if err := g.is.Restore(img, version.Version); err != nil {
+		var vd views.Data
+		vd.SetAlert(err)
+		g.renderImageVersions(w, r, vd, gallery, img)
+		return
+	}
+	g.redirectToImageVersions(w, r, img, fmt.Sprintf("Version %d restored.", img.Version))
+}
+
+// imageVersion looks up the earlier version of an image named in the URL.
+func (g *Galleries) imageVersion(w http.ResponseWriter, r *http.Request,
+	gallery *models.Gallery) (*models.ImageVersion, error) {
+	img := imageFromURL(w, r, gallery)
+	if img == nil {
+		return nil, models.ErrNotFound
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return nil, err
+	}
+	versions, err := g.is.Versions(img)
+	if err != nil {
+		http.Error(w, "Something went wrong.", http.StatusInternalServerError)
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Version == n {
+			return &versions[i], nil
+		}
+	}
+	http.Error(w, "Version not found", http.StatusNotFound)
+	return nil, models.ErrNotFound
+}
+
+func (g *Galleries) redirectToImageVersions(w http.ResponseWriter, r *http.Request, img *models.Image,
+	message string) {
+	path := fmt.Sprintf("/galleries/%d/images/%s/versions", img.GalleryID, url.PathEscape(img.Filename))
+	views.RedirectAlert(w, r, path, http.StatusFound, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: message,
+	})
+}
@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 
 	"github.com/gorilla/schema"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
 )
 
 func parseForm(r *http.Request, dst interface{}) error {
@@ -30,3 +35,89 @@ func parseURLParams(r *http.Request, dst interface{}) error {
 	}
 	return parseValues(r.Form, dst)
 }
+
+// clientIP returns the IP address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// overTLS reports whether the request came over HTTPS, either directly or through a proxy that terminates TLS.
+func overTLS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// absoluteURL turns a path on this site into a full URL, eg for links that are copied and sent to other people.
+func absoluteURL(r *http.Request, path string) string {
+	scheme := "http"
+	if overTLS(r) {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   path,
+	}
+	return u.String()
+}
+
+// FilesOnly returns a file system for http.FileServer that serves the files in dir, and responds to requests for
+// directories with a 404 rather than a listing of what is in them.
+func FilesOnly(dir string) http.FileSystem {
+	return filesOnly{http.Dir(dir)}
+}
+
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (fs filesOnly) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// serveRegularFile serves the file at path, responding with a 404 if it is missing or isn't a regular file, since
+// http.ServeFile would happily list a directory. If prepare isn't nil it is called with the file's info just before
+// the file is served, eg to set headers that depend on it.
+func serveRegularFile(w http.ResponseWriter, r *http.Request, path string, prepare func(info os.FileInfo)) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	if prepare != nil {
+		prepare(info)
+	}
+	http.ServeFile(w, r, path)
+}
+
+// loadCovers loads the images of galleries being listed, so that each can show its cover. The images of a password
+// protected gallery can't be seen until it is unlocked, so those get no cover unless the list is being shown to their
+// owner.
+func loadCovers(is interfaces.ImageServiceInt, galleries []models.Gallery, owner bool) {
+	for i := range galleries {
+		gallery := &galleries[i]
+		if gallery.HasPassword() && !owner {
+			continue
+		}
+		images, err := is.ByGalleryID(gallery.ID)
+		if err != nil {
+			continue
+		}
+		gallery.Images = images
+	}
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeRegularFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.jpg")
+	if err := ioutil.WriteFile(file, []byte("jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"file", file, http.StatusOK},
+		{"directory", dir, http.StatusNotFound},
+		{"missing", filepath.Join(dir, "b.jpg"), http.StatusNotFound},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		prepared := false
+		serveRegularFile(w, httptest.NewRequest("GET", "/", nil), test.path, func(info os.FileInfo) {
+			prepared = true
+			w.Header().Set("ETag", `"1"`)
+		})
+		if w.Code != test.want {
+			t.Errorf("%s: served with status %d, want %d", test.name, w.Code, test.want)
+		}
+		if prepared != (test.want == http.StatusOK) {
+			t.Errorf("%s: prepare called = %v, want %v", test.name, prepared, !prepared)
+		}
+		if test.want == http.StatusOK && w.Header().Get("ETag") != `"1"` {
+			t.Errorf("%s: headers set in prepare weren't sent", test.name)
+		}
+	}
+}
@@ -29,7 +29,13 @@ type Users struct {
 	LoginView    *views.View
 	ForgotPwView *views.View
 	ResetPwView  *views.View
+	AccountView  *views.View
+	ProfileView  *views.View
+	FeedView     *views.View
 	us           interfaces.UserServiceInt
+	gs           interfaces.GalleryServiceInt
+	is           interfaces.ImageServiceInt
+	fs           interfaces.FollowServiceInt
 	emailer      *email.Client
 	r            *mux.Router
 }
@@ -60,13 +66,20 @@ type Alert struct {
 	Message string
 }
 
-func NewUsers(us interfaces.UserServiceInt, r *mux.Router, emailer *email.Client) *Users {
+func NewUsers(us interfaces.UserServiceInt, gs interfaces.GalleryServiceInt, is interfaces.ImageServiceInt,
+	fs interfaces.FollowServiceInt, r *mux.Router, emailer *email.Client) *Users {
 	return &Users{
 		NewView:      views.NewView("bootstrap", "users/new"),
 		LoginView:    views.NewView("bootstrap", "users/login"),
 		ForgotPwView: views.NewView("bootstrap", "users/forgot_pw"),
 		ResetPwView:  views.NewView("bootstrap", "users/reset_pw"),
+		AccountView:  views.NewView("bootstrap", "users/account"),
+		ProfileView:  views.NewView("bootstrap", "users/profile"),
+		FeedView:     views.NewView("bootstrap", "users/feed"),
 		us:           us,
+		gs:           gs,
+		is:           is,
+		fs:           fs,
 		emailer:      emailer,
 		r:            r,
 	}
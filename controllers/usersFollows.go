@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+// activityItem is a feed item along with who it is by and where it links to.
+type activityItem struct {
+	models.FeedItem
+	Owner *models.User
+	URL   string
+}
+
+// feedPage is what the users/feed template expects.
+type feedPage struct {
+	Items      []activityItem
+	Pagination *views.Pagination
+}
+
+// setFollow fills in the profile page's follower count, and whether the current user follows the profile. Users can't
+// follow themselves, and visitors have to sign in first.
+func (u *Users) setFollow(r *http.Request, page *profilePage) {
+	count, err := u.fs.CountFollowers(page.Profile.ID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	page.Followers = count
+	user := context.User(r.Context())
+	if user == nil || user.ID == page.Profile.ID {
+		return
+	}
+	page.CanFollow = true
+	_, err = u.fs.Find(user.ID, page.Profile.ID)
+	switch err {
+	case nil:
+		page.Following = true
+	case models.ErrNotFound:
+	default:
+		log.Println(err)
+	}
+}
+
+// Follow follows a photographer, or unfollows them if the user already follows them.
+//
+// POST /u/:username/follow
+func (u *Users) Follow(w http.ResponseWriter, r *http.Request) {
+	profile, err := u.us.ByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return
+	}
+	user := context.User(r.Context())
+	var vd views.Data
+	following, err := u.fs.Toggle(user.ID, profile.ID)
+	switch {
+	case err != nil:
+		vd.SetAlert(err)
+	case following:
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "You are now following @" + profile.Username + ". Their new galleries will show up in your feed.",
+		}
+	default:
+		vd.Alert = &views.Alert{
+			Level:   views.AlertLvlSuccess,
+			Message: "You are no longer following @" + profile.Username + ".",
+		}
+	}
+	profileURL, err := u.r.Get(ShowProfile).URL("username", profile.Username)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, profileURL.Path, http.StatusFound, *vd.Alert)
+}
+
+// Feed lists what has happened lately in the public galleries of the photographers the user follows, newest first,
+// a page at a time.
+//
+// GET /feed
+func (u *Users) Feed(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	params := r.URL.Query()
+	var vd views.Data
+	feed, err := u.fs.Feed(models.FeedQuery{
+		UserID: user.ID,
+		After:  params.Get("after"),
+		Before: params.Get("before"),
+	})
+	if err != nil {
+		vd.SetAlert(err)
+		vd.Yield = feedPage{}
+		u.FeedView.Render(w, r, vd)
+		return
+	}
+	owners := make(map[uint]*models.User)
+	images := make(map[uint][]models.Image)
+	items := make([]activityItem, len(feed.Items))
+	for i, item := range feed.Items {
+		gallery := item.Gallery
+		owner, ok := owners[gallery.UserID]
+		if !ok {
+			owner, err = u.us.ByID(gallery.UserID)
+			if err != nil {
+				http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+				return
+			}
+			owners[gallery.UserID] = owner
+		}
+		// The images of a password protected gallery can't be seen until it is unlocked, so only say what happened
+		if gallery.HasPassword() {
+			item.Images = nil
+		} else if item.Published() {
+			if _, ok := images[gallery.ID]; !ok {
+				if images[gallery.ID], err = u.is.ByGalleryID(gallery.ID); err != nil {
+					log.Println(err)
+				}
+			}
+			gallery.Images = images[gallery.ID]
+		}
+		items[i] = activityItem{
+			FeedItem: item,
+			Owner:    owner,
+			URL:      galleryPath(u.r, gallery, owner.Username),
+		}
+	}
+	vd.Yield = feedPage{
+		Items:      items,
+		Pagination: views.NewPagination(r.URL, feed.PrevCursor, feed.NextCursor),
+	}
+	u.FeedView.Render(w, r, vd)
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"lenslocked.com/context"
+	"lenslocked.com/models"
+	"lenslocked.com/views"
+)
+
+const (
+	ShowProfile  = "show_profile"
+	EditAccount  = "edit_account"
+	maxAvatarMem = 1 << 20 // 1 megabyte
+)
+
+// AccountForm is used to update the parts of a user's account shown on their profile.
+type AccountForm struct {
+	Name     string `schema:"name"`
+	Username string `schema:"username"`
+	Bio      string `schema:"bio"`
+	Website  string `schema:"website"`
+}
+
+// profilePage is what the users/profile template expects.
+type profilePage struct {
+	Profile    *models.User
+	Galleries  []models.Gallery
+	Pagination *views.Pagination
+	Followers  int
+	Following  bool
+	CanFollow  bool
+}
+
+// Account shows the account settings form.
+//
+// GET /account
+func (u *Users) Account(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	var vd views.Data
+	vd.Yield = user
+	u.AccountView.Render(w, r, vd)
+}
+
+// UpdateAccount saves the account settings form.
+//
+// POST /account
+func (u *Users) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	var vd views.Data
+	vd.Yield = user
+	var form AccountForm
+	if err := parseForm(r, &form); err != nil {
+		vd.SetAlert(err)
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	user.Name = form.Name
+	user.Username = form.Username
+	user.Bio = form.Bio
+	user.Website = form.Website
+	if err := u.us.Update(user); err != nil {
+		vd.SetAlert(err)
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	u.redirectToAccount(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Your account has been updated.",
+	})
+}
+
+// UploadAvatar replaces the user's avatar.
+//
+// POST /account/avatar
+func (u *Users) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	user := context.User(r.Context())
+	var vd views.Data
+	vd.Yield = user
+	if err := r.ParseMultipartForm(maxAvatarMem); err != nil {
+		vd.SetAlert(err)
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	file, header, err := r.FormFile("avatar")
+	if err != nil {
+		vd.AlertError("Please choose an image to upload.")
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	defer file.Close()
+	filename, err := u.is.CreateAvatar(user.ID, file, header.Filename)
+	if err != nil {
+		vd.SetAlert(err)
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	user.Avatar = filename
+	if err := u.us.Update(user); err != nil {
+		vd.SetAlert(err)
+		u.AccountView.Render(w, r, vd)
+		return
+	}
+	u.redirectToAccount(w, r, views.Alert{
+		Level:   views.AlertLvlSuccess,
+		Message: "Your avatar has been updated.",
+	})
+}
+
+func (u *Users) redirectToAccount(w http.ResponseWriter, r *http.Request, alert views.Alert) {
+	url, err := u.r.Get(EditAccount).URL()
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	views.RedirectAlert(w, r, url.Path, http.StatusFound, alert)
+}
+
+// Profile is a photographer's public page, listing their public galleries a page at a time.
+//
+// GET /u/:username
+func (u *Users) Profile(w http.ResponseWriter, r *http.Request) {
+	profile, err := u.us.ByUsername(mux.Vars(r)["username"])
+	if err != nil {
+		switch err {
+		case models.ErrNotFound:
+			http.Error(w, "User not found", http.StatusNotFound)
+		default:
+			http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		}
+		return
+	}
+	var vd views.Data
+	params := r.URL.Query()
+	galleries, err := u.gs.Page(models.GalleryQuery{
+		UserID:     profile.ID,
+		Sort:       models.SortCreated,
+		Desc:       true,
+		Visibility: models.VisibilityPublic,
+		After:      params.Get("after"),
+		Before:     params.Get("before"),
+	})
+	switch err {
+	case nil:
+	case models.ErrCursorInvalid:
+		vd.SetAlert(err)
+		galleries = &models.GalleryPage{}
+	default:
+		http.Error(w, "Whoops! Something went wrong.", http.StatusInternalServerError)
+		return
+	}
+	loadCovers(u.is, galleries.Galleries, false)
+	vd.Meta = u.profileMeta(r, profile)
+	page := profilePage{
+		Profile:    profile,
+		Galleries:  galleries.Galleries,
+		Pagination: views.NewPagination(r.URL, galleries.PrevCursor, galleries.NextCursor),
+	}
+	u.setFollow(r, &page)
+	vd.Yield = page
+	u.ProfileView.Render(w, r, vd)
+}
+
+// profileMeta describes a profile for search engines and link previews.
+func (u *Users) profileMeta(r *http.Request, profile *models.User) *views.Meta {
+	meta := views.Meta{
+		Title:       profile.Name + " (@" + profile.Username + ")",
+		Description: views.Summarize(profile.Bio),
+		Type:        "profile",
+	}
+	if profile.Name == "" {
+		meta.Title = "@" + profile.Username
+	}
+	if profile.Avatar != "" {
+		meta.Image = absoluteURL(r, "/"+profile.AvatarRelativePath())
+	}
+	if profileURL, err := u.r.Get(ShowProfile).URL("username", profile.Username); err == nil {
+		meta.URL = absoluteURL(r, profileURL.Path)
+	}
+	if link, ok := feedLink(u.r, r, profile); ok {
+		meta.Links = append(meta.Links, link)
+	}
+	return &meta
+}
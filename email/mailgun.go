@@ -2,15 +2,27 @@ package email
 
 import (
 	"fmt"
+	"html/template"
 	"net/url"
 
 	mailgun "gopkg.in/mailgun/mailgun-go.v1"
+
+	"lenslocked.com/models"
 )
 
 const (
 	welcomeSubject = "Welcome to LensLocked.com!"
 	resetSubject   = "Instructions for resetting your password."
 	resetBaseURL   = "https://www.lenslocked.com/reset"
+
+	selectionSubjectTmpl = "%s submitted their selection for %q"
+	selectionURLTmpl     = "https://www.lenslocked.com/galleries/%d/selections"
+	inviteSubjectTmpl    = "You have been invited to work on %q"
+	expiringSubjectTmpl  = "Your gallery %q expires in a week"
+	commentSubjectTmpl   = "%s commented on %q"
+	transferSubjectTmpl  = "%s would like to give you the gallery %q"
+	showGalleryURLTmpl   = "https://www.lenslocked.com/galleries/%d"
+	editGalleryURLTmpl   = "https://www.lenslocked.com/galleries/%d/edit"
 )
 
 const welcomeText = `Hi there!
@@ -63,6 +75,142 @@ Best,<br/>
 LensLocked Support<br/>
 `
 
+const selectionTextTmpl = `Hi there!
+
+%s (%s) has submitted their selection of %d image(s) from your gallery %q.
+
+Their note:
+
+%s
+
+You can review the selection and download the list of filenames here:
+
+%s
+
+Best,
+LensLocked Support
+`
+
+const selectionHTMLTmpl = `Hi there!<br/>
+<br/>
+%s (%s) has submitted their selection of %d image(s) from your gallery %q.<br/>
+<br/>
+Their note:<br/>
+<br/>
+%s<br/>
+<br/>
+You can review the selection and download the list of filenames here:<br/>
+<br/>
+<a href="%s">%s</a><br/>
+<br/>
+Best,<br/>
+LensLocked Support<br/>
+`
+const inviteTextTmpl = `Hi there!
+
+%s has invited you to join their gallery %q on LensLocked.com as a %s.
+
+To accept the invitation, sign in (or sign up) and follow the link below:
+
+%s
+
+If you weren't expecting this invitation you can safely ignore this email.
+
+Best,
+LensLocked Support
+`
+
+const inviteHTMLTmpl = `Hi there!<br/>
+<br/>
+%s has invited you to join their gallery %q on LensLocked.com as a %s.<br/>
+<br/>
+To accept the invitation, sign in (or sign up) and follow the link below:<br/>
+<br/>
+<a href="%s">%s</a><br/>
+<br/>
+If you weren't expecting this invitation you can safely ignore this email.<br/>
+<br/>
+Best,<br/>
+LensLocked Support<br/>
+`
+
+const expiringTextTmpl = `Hi there!
+
+Your gallery %q will expire on %s. After that, visitors will see a page saying the gallery has expired.
+
+If you would like to keep it up for longer, you can change or remove the expiry date here:
+
+%s
+
+Best,
+LensLocked Support
+`
+
+const expiringHTMLTmpl = `Hi there!<br/>
+<br/>
+Your gallery %q will expire on %s. After that, visitors will see a page saying the gallery has expired.<br/>
+<br/>
+If you would like to keep it up for longer, you can change or remove the expiry date here:<br/>
+<br/>
+<a href="%s">%s</a><br/>
+<br/>
+Best,<br/>
+LensLocked Support<br/>
+`
+
+const commentTextTmpl = `Hi there!
+
+%s left a comment on %s:
+
+%s
+
+%s
+
+Best,
+LensLocked Support
+`
+
+const commentHTMLTmpl = `Hi there!<br/>
+<br/>
+%s left a comment on %s:<br/>
+<br/>
+<blockquote>%s</blockquote>
+<br/>
+%s<br/>
+<br/>
+Best,<br/>
+LensLocked Support<br/>
+`
+
+const transferTextTmpl = `Hi there!
+
+%s would like to hand their gallery %q over to you on LensLocked.com. If you accept, the gallery, its images and its
+share links will belong to your account.
+
+To accept, sign in (or sign up) with this email address and follow the link below. The link expires on %s.
+
+%s
+
+If you weren't expecting this you can safely ignore this email.
+
+Best,
+LensLocked Support
+`
+
+const transferHTMLTmpl = `Hi there!<br/>
+<br/>
+%s would like to hand their gallery %q over to you on LensLocked.com. If you accept, the gallery, its images and its
+share links will belong to your account.<br/>
+<br/>
+To accept, sign in (or sign up) with this email address and follow the link below. The link expires on %s.<br/>
+<br/>
+<a href="%s">%s</a><br/>
+<br/>
+If you weren't expecting this you can safely ignore this email.<br/>
+<br/>
+Best,<br/>
+LensLocked Support<br/>
+`
 
 func WithMailgun(domain, apiKey, publicKey string) ClientConfig {
 	return func(c *Client) {
@@ -120,3 +268,89 @@ func buildEmail(name, email string) string {
 	}
 	return fmt.Sprintf("%s <%s>", name, email)
 }
+
+// SelectionSubmitted lets a photographer know that a client has finished proofing one of their galleries.
+func (c *Client) SelectionSubmitted(toEmail string, gallery *models.Gallery, selection *models.Selection) error {
+	subject := fmt.Sprintf(selectionSubjectTmpl, selection.Name, gallery.Title)
+	selectionUrl := fmt.Sprintf(selectionURLTmpl, gallery.ID)
+	note := selection.Note
+	if note == "" {
+		note = "(none)"
+	}
+	text := fmt.Sprintf(selectionTextTmpl, selection.Name, selection.Email, len(selection.Images),
+		gallery.Title, note, selectionUrl)
+	message := mailgun.NewMessage(c.from, subject, text, toEmail)
+	html := fmt.Sprintf(selectionHTMLTmpl, template.HTMLEscapeString(selection.Name),
+		template.HTMLEscapeString(selection.Email), len(selection.Images), template.HTMLEscapeString(gallery.Title),
+		template.HTMLEscapeString(note), selectionUrl, selectionUrl)
+	message.SetHtml(html)
+	_, _, err := c.mg.Send(message)
+	return err
+}
+
+// Invite asks someone to collaborate on a gallery. acceptUrl is the link that accepts the invitation.
+func (c *Client) Invite(toEmail, fromName string, gallery *models.Gallery, role, acceptUrl string) error {
+	if fromName == "" {
+		fromName = "A LensLocked.com photographer"
+	}
+	subject := fmt.Sprintf(inviteSubjectTmpl, gallery.Title)
+	text := fmt.Sprintf(inviteTextTmpl, fromName, gallery.Title, role, acceptUrl)
+	message := mailgun.NewMessage(c.from, subject, text, toEmail)
+	html := fmt.Sprintf(inviteHTMLTmpl, template.HTMLEscapeString(fromName),
+		template.HTMLEscapeString(gallery.Title), role, acceptUrl, acceptUrl)
+	message.SetHtml(html)
+	_, _, err := c.mg.Send(message)
+	return err
+}
+
+// GalleryExpiring reminds a photographer that one of their galleries is about to expire.
+func (c *Client) GalleryExpiring(toEmail string, gallery *models.Gallery) error {
+	subject := fmt.Sprintf(expiringSubjectTmpl, gallery.Title)
+	editUrl := fmt.Sprintf(editGalleryURLTmpl, gallery.ID)
+	expires := gallery.ExpireAt.UTC().Format("Monday, January 2 at 15:04 UTC")
+	text := fmt.Sprintf(expiringTextTmpl, gallery.Title, expires, editUrl)
+	message := mailgun.NewMessage(c.from, subject, text, toEmail)
+	html := fmt.Sprintf(expiringHTMLTmpl, template.HTMLEscapeString(gallery.Title), expires, editUrl, editUrl)
+	message.SetHtml(html)
+	_, _, err := c.mg.Send(message)
+	return err
+}
+
+// CommentPosted lets a photographer know that a visitor has commented on one of their galleries, and whether the
+// comment is waiting for them to approve it.
+func (c *Client) CommentPosted(toEmail string, gallery *models.Gallery, comment *models.Comment) error {
+	subject := fmt.Sprintf(commentSubjectTmpl, comment.Name, gallery.Title)
+	galleryUrl := fmt.Sprintf(showGalleryURLTmpl, gallery.ID)
+	on := fmt.Sprintf("your gallery %q", gallery.Title)
+	if comment.OnImage() {
+		on = fmt.Sprintf("%s in your gallery %q", comment.Filename, gallery.Title)
+	}
+	action := "You can read it here: " + galleryUrl
+	if !comment.Approved {
+		action = "It won't be shown until you approve it here: " + galleryUrl
+	}
+	text := fmt.Sprintf(commentTextTmpl, comment.Name, on, comment.Body, action)
+	message := mailgun.NewMessage(c.from, subject, text, toEmail)
+	html := fmt.Sprintf(commentHTMLTmpl, template.HTMLEscapeString(comment.Name), template.HTMLEscapeString(on),
+		template.HTMLEscapeString(comment.Body), template.HTMLEscapeString(action))
+	message.SetHtml(html)
+	_, _, err := c.mg.Send(message)
+	return err
+}
+
+// TransferRequested offers someone ownership of a gallery. acceptUrl is the link that accepts the transfer.
+func (c *Client) TransferRequested(toEmail, fromName string, gallery *models.Gallery, transfer *models.Transfer,
+	acceptUrl string) error {
+	if fromName == "" {
+		fromName = "A LensLocked.com photographer"
+	}
+	subject := fmt.Sprintf(transferSubjectTmpl, fromName, gallery.Title)
+	expires := transfer.ExpiresAt.UTC().Format("Monday, January 2 at 15:04 UTC")
+	text := fmt.Sprintf(transferTextTmpl, fromName, gallery.Title, expires, acceptUrl)
+	message := mailgun.NewMessage(c.from, subject, text, toEmail)
+	html := fmt.Sprintf(transferHTMLTmpl, template.HTMLEscapeString(fromName),
+		template.HTMLEscapeString(gallery.Title), expires, acceptUrl, acceptUrl)
+	message.SetHtml(html)
+	_, _, err := c.mg.Send(message)
+	return err
+}
@@ -0,0 +1,31 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type CollaboratorDBInt interface {
+	ByID(id uint) (*models.Collaborator, error)
+	ByToken(token string) (*models.Collaborator, error)
+	ByGalleryID(galleryID uint) ([]models.Collaborator, error)
+	// ByUserID returns the invitations the user has accepted
+	ByUserID(userID uint) ([]models.Collaborator, error)
+	ByGalleryAndEmail(galleryID uint, email string) (*models.Collaborator, error)
+	ByGalleryAndUser(galleryID, userID uint) (*models.Collaborator, error)
+	Create(collaborator *models.Collaborator) error
+	Update(collaborator *models.Collaborator) error
+	// Claim accepts the invitation for the user, returning
+	// ErrTokenInvalid if it has already been accepted.
+	Claim(collaborator *models.Collaborator, userID uint) error
+	Delete(id uint) error
+}
+
+type CollaboratorServiceInt interface {
+	// Accept binds the invitation with the given token to the
+	// user. ErrTokenInvalid is returned if there is no such
+	// invitation, it was sent to another email address, or
+	// another user already accepted it.
+	Accept(token string, user *models.User) (*models.Collaborator, error)
+	// Role returns the role the user has been given on the
+	// gallery, or an empty string if they have none.
+	Role(galleryID, userID uint) string
+	CollaboratorDBInt
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type CollectionDBInt interface {
+	ByID(id uint) (*models.Collection, error)
+	// ByUserID returns all of the user's collections in tree
+	// order, so each collection is followed by the ones inside
+	// it, with Depth set.
+	ByUserID(userID uint) ([]models.Collection, error)
+	ByParentID(parentID uint) ([]models.Collection, error)
+	Create(collection *models.Collection) error
+	Update(collection *models.Collection) error
+	// Delete removes the collection and moves everything that
+	// was inside it up into its parent.
+	Delete(id uint) error
+}
+
+type CollectionServiceInt interface {
+	// Breadcrumbs returns the collection with the given ID and
+	// every collection above it, starting from the top.
+	Breadcrumbs(id uint) ([]models.Collection, error)
+	CollectionDBInt
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type CommentDBInt interface {
+	ByID(id uint) (*models.Comment, error)
+	// ByGalleryID returns the comments on a gallery and its
+	// images, oldest first. Unless all is set only approved
+	// comments are returned.
+	ByGalleryID(galleryID uint, all bool) ([]models.Comment, error)
+	Create(comment *models.Comment) error
+	Update(comment *models.Comment) error
+	Delete(id uint) error
+}
+
+type CommentServiceInt interface {
+	Approve(comment *models.Comment) error
+	CommentDBInt
+}
@@ -0,0 +1,23 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type FollowDBInt interface {
+	// Find returns the follower's follow of the followee.
+	Find(followerID, followeeID uint) (*models.Follow, error)
+	// CountFollowers returns how many users follow the user.
+	CountFollowers(userID uint) (int, error)
+	// Feed returns a page of what has happened in the public
+	// galleries of the users the user follows, newest first.
+	Feed(query models.FeedQuery) (*models.FeedPage, error)
+	Create(follow *models.Follow) error
+	Delete(id uint) error
+}
+
+type FollowServiceInt interface {
+	// Toggle follows a user, or unfollows them if the follower
+	// already follows them, and reports whether the follower
+	// follows them now.
+	Toggle(followerID, followeeID uint) (bool, error)
+	FollowDBInt
+}
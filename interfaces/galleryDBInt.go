@@ -1,11 +1,50 @@
 package interfaces
 
-import "lenslocked.com/models"
+import (
+	"time"
+
+	"lenslocked.com/models"
+)
 
 type GalleryDBInt interface {
 	ByID(id uint) (*models.Gallery, error)
+	// BySlug returns the user's gallery with the given slug.
+	BySlug(userID uint, slug string) (*models.Gallery, error)
 	ByUserID(userID uint) ([]models.Gallery, error)
+	ByCollectionID(collectionID uint) ([]models.Gallery, error)
+	// Page returns one page of a user's galleries, sorted and
+	// filtered as described by the query.
+	Page(query models.GalleryQuery) (*models.GalleryPage, error)
+	// Published returns the most recently published public
+	// galleries of the user, or of everyone if userID is 0,
+	// newest first.
+	Published(userID uint, limit int) ([]models.Gallery, error)
+	// Search returns the user's galleries whose title, tags or
+	// image captions match the query, best matches first.
+	Search(userID uint, query string) ([]models.GallerySearchResult, error)
 	Create(gallery *models.Gallery) error
 	Update(gallery *models.Gallery) error
+	// Delete moves the gallery to the trash. Galleries in the
+	// trash aren't returned by any of the methods above.
 	Delete(id uint) error
+	// SlugTaken reports whether any other of the user's
+	// galleries, including those in the trash, has the slug.
+	SlugTaken(userID, galleryID uint, slug string) (bool, error)
+
+	// ExpiringBefore returns the galleries that expire before
+	// the given time whose owners want to be told, and haven't
+	// been yet.
+	ExpiringBefore(t time.Time) ([]models.Gallery, error)
+	MarkExpiryNotified(id uint) error
+
+	// Methods for the trash
+	TrashedByID(id uint) (*models.Gallery, error)
+	TrashedByUserID(userID uint) ([]models.Gallery, error)
+	// TrashedBefore returns every gallery that was moved to the
+	// trash before the given time.
+	TrashedBefore(t time.Time) ([]models.Gallery, error)
+	Restore(id uint) error
+	// DeleteForever removes a gallery in the trash, along with
+	// its images and everything else stored for it.
+	DeleteForever(id uint) error
 }
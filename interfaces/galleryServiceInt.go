@@ -1,10 +1,33 @@
 package interfaces
 
+import (
+	"time"
+
+	"lenslocked.com/models"
+)
+
 // UserDBInt is implemented by userGorm
 // UserServiceInt is implemented by userService
 // GalleryDBInt => galleryGorm
 // GalleryServiceInt => GalleryService
 
 type GalleryServiceInt interface {
+	// Unlock will verify the password for a password
+	// protected gallery. If it is correct, a signed token is
+	// returned that can be handed to Unlocked later on to
+	// prove the visitor knew the password. Otherwise you will
+	// receive ErrPasswordIncorrect, or another error if
+	// something goes wrong.
+	Unlock(gallery *models.Gallery, password string) (string, error)
+	// Unlocked reports whether the token was returned by
+	// Unlock for this gallery and its current password.
+	Unlocked(gallery *models.Gallery, token string) bool
+	// PurgeAt returns when a gallery in the trash will be
+	// deleted forever.
+	PurgeAt(gallery *models.Gallery) time.Time
+	// PurgeTrash deletes forever every gallery that has been in
+	// the trash for longer than the retention period, and
+	// returns how many were deleted.
+	PurgeTrash() (int, error)
 	GalleryDBInt
 }
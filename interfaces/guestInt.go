@@ -0,0 +1,41 @@
+package interfaces
+
+import (
+	"io"
+
+	"lenslocked.com/models"
+)
+
+type GuestDBInt interface {
+	LinkByID(id uint) (*models.GuestLink, error)
+	LinkByToken(token string) (*models.GuestLink, error)
+	LinksByGalleryID(galleryID uint) ([]models.GuestLink, error)
+	CreateLink(link *models.GuestLink) error
+	UpdateLink(link *models.GuestLink) error
+	// RevokeLink stops the link from taking any more photos.
+	RevokeLink(link *models.GuestLink) error
+	// ClaimUpload counts an upload against the link, returning
+	// ErrGuestUploadsUsed if it can't take any more.
+	ClaimUpload(link *models.GuestLink) error
+	UploadByID(id uint) (*models.GuestUpload, error)
+	// Pending returns the gallery's uploads that are waiting to
+	// be approved, oldest first.
+	Pending(galleryID uint) ([]models.GuestUpload, error)
+	CreateUpload(upload *models.GuestUpload) error
+	DeleteUpload(id uint) error
+}
+
+type GuestServiceInt interface {
+	// Upload adds a photo uploaded with a guest link to its
+	// gallery's moderation queue. ErrGuestLinkInvalid is
+	// returned if the link has been revoked, and
+	// ErrGuestUploadsUsed or ErrGuestImageTooBig if the photo
+	// is over the link's limits.
+	Upload(link *models.GuestLink, guestName string, r io.Reader, filename string) (*models.GuestUpload, error)
+	// Approve moves an upload into its gallery, crediting the
+	// guest who uploaded it.
+	Approve(upload *models.GuestUpload) error
+	// Reject deletes an upload.
+	Reject(upload *models.GuestUpload) error
+	GuestDBInt
+}
@@ -9,5 +9,28 @@ import (
 type ImageServiceInt interface {
 	Create(galleryID uint, r io.Reader, filename string) error
 	ByGalleryID(galleryID uint) ([]models.Image, error)
+	// Update saves the details of an image that aren't stored
+	// on disk, like its caption.
+	Update(i *models.Image) error
 	Delete(i *models.Image) error
+	// Replace stores a new version of an image under the same
+	// filename, keeping the current version so that it can be
+	// restored.
+	Replace(i *models.Image, r io.Reader) error
+	// Versions returns the earlier versions of an image, newest
+	// first.
+	Versions(i *models.Image) ([]models.ImageVersion, error)
+	// Restore makes an earlier version of an image the current
+	// one, keeping the current one as an earlier version.
+	Restore(i *models.Image, version int) error
+	// CurrentVersion returns the version of an image that is
+	// served at its path.
+	CurrentVersion(galleryID uint, filename string) (int, error)
+	// Copy copies every image in one gallery, along with its
+	// details, into another gallery.
+	Copy(fromGalleryID, toGalleryID uint) error
+	// CreateAvatar replaces the user's avatar with the given
+	// image. The caller is responsible for saving the returned
+	// filename as the user's Avatar.
+	CreateAvatar(userID uint, r io.Reader, filename string) (string, error)
 }
@@ -0,0 +1,29 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type LikeDBInt interface {
+	// ByUserID returns everything the user has liked, most
+	// recent first.
+	ByUserID(userID uint) ([]models.Like, error)
+	// ByUserAndGallery returns the user's likes of a gallery
+	// and its images.
+	ByUserAndGallery(userID, galleryID uint) ([]models.Like, error)
+	// Find returns the user's like of a gallery (when filename
+	// is empty) or one of its images.
+	Find(userID, galleryID uint, filename string) (*models.Like, error)
+	// Counts returns how many likes a gallery and each of its
+	// images has, keyed by filename. The gallery's own count is
+	// under the empty filename.
+	Counts(galleryID uint) (map[string]int, error)
+	Create(like *models.Like) error
+	Delete(id uint) error
+}
+
+type LikeServiceInt interface {
+	// Toggle likes a gallery or image, or unlikes it if the user
+	// already likes it, and reports whether the user likes it
+	// now.
+	Toggle(userID, galleryID uint, filename string) (bool, error)
+	LikeDBInt
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+// SelectionDBInt is used to interact with the proofing selections stored in the database.
+type SelectionDBInt interface {
+	ByID(id uint) (*models.Selection, error)
+	ByToken(token string) (*models.Selection, error)
+	ByGalleryID(galleryID uint) ([]models.Selection, error)
+	Create(selection *models.Selection) error
+	Update(selection *models.Selection) error
+
+	// Methods for the images hearted in a selection
+	Images(selectionID uint) ([]models.SelectionImage, error)
+	Heart(selectionID uint, filename string) error
+	Unheart(selectionID uint, filename string) error
+}
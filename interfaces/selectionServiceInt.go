@@ -0,0 +1,15 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type SelectionServiceInt interface {
+	// Toggle will heart the image with the given filename if
+	// it is not yet part of the selection, and unheart it
+	// otherwise. ErrSelectionSubmitted is returned once the
+	// selection has been submitted.
+	Toggle(selection *models.Selection, filename string) error
+	// Submit marks the selection as final. After this the
+	// client can no longer change it.
+	Submit(selection *models.Selection) error
+	SelectionDBInt
+}
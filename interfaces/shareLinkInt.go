@@ -0,0 +1,26 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type ShareLinkDBInt interface {
+	ByID(id uint) (*models.ShareLink, error)
+	ByToken(token string) (*models.ShareLink, error)
+	ByGalleryID(galleryID uint) ([]models.ShareLink, error)
+	Create(link *models.ShareLink) error
+	Update(link *models.ShareLink) error
+	// ClaimView counts a view against the link, returning
+	// ErrShareLinkInvalid if it is no longer valid or has used
+	// up all of its views.
+	ClaimView(link *models.ShareLink) error
+	// Revoke stops the link from granting access.
+	Revoke(link *models.ShareLink) error
+}
+
+type ShareLinkServiceInt interface {
+	// Visit looks up the share link for the token and counts
+	// a view against it. ErrShareLinkInvalid is returned if
+	// the link is unknown, revoked, expired or has used up
+	// all of its views.
+	Visit(token string) (*models.ShareLink, error)
+	ShareLinkDBInt
+}
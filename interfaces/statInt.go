@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"time"
+
+	"lenslocked.com/models"
+)
+
+type StatDBInt interface {
+	// RecordView counts a view of a gallery page, or of one of
+	// its images if filename isn't empty.
+	RecordView(galleryID uint, filename string) error
+	RecordDownload(galleryID uint, filename string) error
+	// ByGalleryID returns the gallery's stats since the given
+	// day, oldest first.
+	ByGalleryID(galleryID uint, since time.Time) ([]models.GalleryStat, error)
+}
+
+type StatServiceInt interface {
+	// Summary totals the gallery's stats for the last number of
+	// days, including the days nobody visited.
+	Summary(galleryID uint, days int) (*models.GalleryStats, error)
+	StatDBInt
+}
@@ -0,0 +1,31 @@
+package interfaces
+
+import "lenslocked.com/models"
+
+type TransferDBInt interface {
+	ByID(id uint) (*models.Transfer, error)
+	ByToken(token string) (*models.Transfer, error)
+	// PendingByGalleryID returns the transfer of the gallery that
+	// is waiting to be accepted, if there is one.
+	PendingByGalleryID(galleryID uint) (*models.Transfer, error)
+	// Create saves a new transfer and records it in the
+	// gallery's audit trail.
+	Create(transfer *models.Transfer) error
+	// Cancel withdraws a transfer and records who did it in the
+	// audit trail.
+	Cancel(transfer *models.Transfer, userID uint) error
+	// Complete hands the gallery over to transfer.ToUserID and
+	// records it in the audit trail, all at once.
+	Complete(transfer *models.Transfer) error
+	// AuditTrail returns everything recorded about a gallery,
+	// most recent first.
+	AuditTrail(galleryID uint) ([]models.AuditEntry, error)
+}
+
+type TransferServiceInt interface {
+	// Accept hands the gallery over to the user, provided the
+	// transfer with the given token is still pending and was
+	// sent to their email address.
+	Accept(token string, user *models.User) (*models.Transfer, error)
+	TransferDBInt
+}
@@ -20,6 +20,7 @@ type UserDBInt interface {
 	ByID(id uint) (*models.User, error)
 	ByEmail(email string) (*models.User, error)
 	ByRemember(token string) (*models.User, error)
+	ByUsername(username string) (*models.User, error)
 
 	// Methods for altering users
 	Create(user *models.User) error
@@ -0,0 +1,39 @@
+// Package jobs runs background work, like purging the trash, on a schedule.
+//
+// Jobs only run inside the web server process, so there is nothing else to deploy. Every job has to be safe to run
+// more than once, since a restart can make a job run again sooner than its interval.
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Every runs fn once straight away and then once every interval, in its own goroutine, for as long as the program
+// runs. Errors are logged under the job's name and don't stop later runs.
+func Every(interval time.Duration, name string, fn func() error) {
+	go func() {
+		for {
+			run(name, fn)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// Go runs fn once, in its own goroutine, logging any error under the job's name. It is for work that is too slow to
+// make a visitor wait for.
+func Go(name string, fn func() error) {
+	go run(name, fn)
+}
+
+// run calls fn, making sure a panic in one run doesn't take the whole server down with it.
+func run(name string, fn func() error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("jobs: %s panicked: %v", name, r)
+		}
+	}()
+	if err := fn(); err != nil {
+		log.Printf("jobs: %s failed: %v", name, err)
+	}
+}
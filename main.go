@@ -4,12 +4,15 @@ import (
 	"flag"
 	"fmt"
 	"lenslocked.com/email"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	"lenslocked.com/controllers"
+	"lenslocked.com/jobs"
 	"lenslocked.com/middleware"
 	"lenslocked.com/rand"
 	"lenslocked.com/services"
@@ -31,20 +34,50 @@ func main() {
 		services.WithGorm(dbCfg.Dialect(), dbCfg.ConnectionInfo()),
 		services.WithLogMode(!cfg.IsProd()),
 		services.WithUser(cfg.Pepper, cfg.HMACKey),
-		services.WithGallery(),
+		services.WithGallery(cfg.Pepper, cfg.HMACKey, cfg.TrashRetention()),
 		services.WithImage(),
+		services.WithSelection(cfg.HMACKey),
+		services.WithShareLink(cfg.HMACKey),
+		services.WithCollaborator(cfg.HMACKey),
+		services.WithCollection(),
+		services.WithStat(),
+		services.WithComment(),
+		services.WithLike(),
+		services.WithTransfer(cfg.HMACKey),
+		services.WithFollow(),
+		services.WithGuest(cfg.HMACKey),
 	)
 	if err != nil {
 		panic(err)
 	}
 	defer services.Close()
-	services.AutoMigrate()
+	if err := services.AutoMigrate(); err != nil {
+		panic(err)
+	}
 	//services.DestructiveReset()
 
+	// Empty the trash of galleries that have been there longer
+	// than the retention period
+	jobs.Every(time.Hour, "purge trash", func() error {
+		n, err := services.Gallery.PurgeTrash()
+		if n > 0 {
+			log.Printf("purged %d galleries from the trash", n)
+		}
+		return err
+	})
+
 	r := mux.NewRouter()
 	staticC := controllers.NewStatic()
-	usersC := controllers.NewUsers(services.User, r, emailer)
-	galleriesC := controllers.NewGalleries(services.Gallery, services.Image, r)
+	usersC := controllers.NewUsers(services.User, services.Gallery, services.Image, services.Follow, r, emailer)
+	galleriesC := controllers.NewGalleries(services.Gallery, services.Image, services.Selection, services.ShareLink,
+		services.Collaborator, services.Collection, services.Stat, services.Comment,
+		services.Like, services.Transfer, services.Guest, services.User, emailer, r)
+	collectionsC := controllers.NewCollections(services.Collection, services.Gallery, services.Image, galleriesC, r)
+	feedsC := controllers.NewFeeds(services.Gallery, services.Image, services.User, galleriesC, r)
+
+	// Remind owners who asked for it that their galleries are
+	// about to expire
+	jobs.Every(time.Hour, "expiry reminders", galleriesC.SendExpiryReminders)
 
 	// Redirects to /login if a user is not signed in
 	requireUserMw := middleware.RequireUser{}
@@ -78,22 +111,155 @@ func main() {
 	indexGallery := requireUserMw.ApplyFn(galleriesC.Index)
 	uploadImage := requireUserMw.ApplyFn(galleriesC.ImageUpload)
 	deleteImage := requireUserMw.ApplyFn(galleriesC.ImageDelete)
+	captionImage := requireUserMw.ApplyFn(galleriesC.ImageCaption)
+	selections := requireUserMw.ApplyFn(galleriesC.Selections)
+	inviteClient := requireUserMw.ApplyFn(galleriesC.InviteClient)
+	setGalleryPassword := requireUserMw.ApplyFn(galleriesC.SetPassword)
+	shareLinks := requireUserMw.ApplyFn(galleriesC.ShareLinks)
+	createShareLink := requireUserMw.ApplyFn(galleriesC.CreateShareLink)
+	revokeShareLink := requireUserMw.ApplyFn(galleriesC.RevokeShareLink)
+	collaborators := requireUserMw.ApplyFn(galleriesC.Collaborators)
+	inviteCollaborator := requireUserMw.ApplyFn(galleriesC.InviteCollaborator)
+	updateCollaborator := requireUserMw.ApplyFn(galleriesC.UpdateCollaborator)
+	removeCollaborator := requireUserMw.ApplyFn(galleriesC.RemoveCollaborator)
+	acceptInvite := requireUserMw.ApplyFn(galleriesC.AcceptInvite)
+	selectionCSV := requireUserMw.ApplyFn(galleriesC.SelectionCSV)
 	r.Handle("/galleries/new", newGallery).Methods("GET")
 	r.HandleFunc("/galleries", createGallery).Methods("POST")
 	// Name the route controllers.ShowGallery
-	r.HandleFunc("/galleries/{id:[0-9]+}", galleriesC.Show).Methods("GET").Name(controllers.ShowGallery)
-	r.HandleFunc("/galleries/{id:[0-9]+}/edit", editGallery).Methods("GET").Name(controllers.EditGallery)
+	r.HandleFunc("/u/{username}/{slug:[a-z0-9-]+}", galleriesC.Show).Methods("GET").Name(controllers.ShowGallery)
+	r.HandleFunc("/u/{username}/{slug:[a-z0-9-]+}/edit", editGallery).Methods("GET").Name(controllers.EditGallery)
+	// Numeric URLs redirect to the ones above when the gallery has a slug
+	r.HandleFunc("/galleries/{id:[0-9]+}", galleriesC.Show).Methods("GET").Name(controllers.ShowGalleryByID)
+	r.HandleFunc("/galleries/{id:[0-9]+}/edit", editGallery).Methods("GET").Name(controllers.EditGalleryByID)
 	r.HandleFunc("/galleries/{id:[0-9]+}/update", updateGallery).Methods("POST")
 	r.HandleFunc("/galleries/{id:[0-9]+}/delete", deleteGallery).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/password", setGalleryPassword).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/unlock", galleriesC.Unlock).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/links", shareLinks).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/links", createShareLink).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/links/{lid:[0-9]+}/revoke", revokeShareLink).Methods("POST")
+	r.HandleFunc("/s/{token}", galleriesC.VisitShareLink).Methods("GET").Name(controllers.VisitShareLink)
+	r.HandleFunc("/galleries/{id:[0-9]+}/collaborators", collaborators).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/collaborators", inviteCollaborator).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/collaborators/{cid:[0-9]+}/update", updateCollaborator).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/collaborators/{cid:[0-9]+}/delete", removeCollaborator).Methods("POST")
+	r.HandleFunc("/invitations/{token}", acceptInvite).Methods("GET").Name(controllers.AcceptInvite)
 	r.Handle("/galleries", indexGallery).Methods("GET").Name(controllers.IndexGalleries)
 	r.HandleFunc("/galleries/{id:[0-9]+}/images", uploadImage).Methods("POST")
-	imageHandler := http.FileServer(http.Dir("./images/"))
-	// http.StripPrefix acts as middleware and removes "/images/" before passing to imageHandler
-	r.PathPrefix("/images/").Handler(http.StripPrefix("/images/", imageHandler))
+	// Images are served through the galleries controller so that gallery visibility applies to them too
+	r.HandleFunc("/images/galleries/{id:[0-9]+}/{filename}", galleriesC.ImageServe).Methods("GET")
 	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/delete", deleteImage).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/caption", captionImage).Methods("POST")
+	// Image versions
+	imageVersions := requireUserMw.ApplyFn(galleriesC.ImageVersions)
+	replaceImage := requireUserMw.ApplyFn(galleriesC.ReplaceImage)
+	imageVersion := requireUserMw.ApplyFn(galleriesC.ImageVersionServe)
+	restoreImageVersion := requireUserMw.ApplyFn(galleriesC.RestoreImageVersion)
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/versions", imageVersions).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/replace", replaceImage).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/versions/{version:[0-9]+}", imageVersion).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/versions/{version:[0-9]+}/restore",
+		restoreImageVersion).Methods("POST")
+
+	// Embedding galleries on other sites
+	r.HandleFunc("/embed/galleries/{id:[0-9]+}", galleriesC.Embed).Methods("GET")
+	r.HandleFunc("/oembed", galleriesC.OEmbed).Methods("GET")
+
+	// Client proofing
+	r.HandleFunc("/galleries/{id:[0-9]+}/proofing", galleriesC.StartProofing).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/proofing/images/{filename}", galleriesC.HeartImage).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/proofing/submit", galleriesC.SubmitSelection).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/proofing/{token}", galleriesC.ProofingLink).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/selections", selections).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/selections", inviteClient).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/selections/{sid:[0-9]+}/csv", selectionCSV).Methods("GET")
+	// Comments can be left by visitors without an account
+	approveComment := requireUserMw.ApplyFn(galleriesC.ApproveComment)
+	deleteComment := requireUserMw.ApplyFn(galleriesC.DeleteComment)
+	r.HandleFunc("/galleries/{id:[0-9]+}/comments", galleriesC.PostComment).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/comments/{cid:[0-9]+}/approve", approveComment).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/comments/{cid:[0-9]+}/delete", deleteComment).Methods("POST")
+	// Likes
+	likeGallery := requireUserMw.ApplyFn(galleriesC.LikeGallery)
+	likeImage := requireUserMw.ApplyFn(galleriesC.LikeImage)
+	favourites := requireUserMw.ApplyFn(galleriesC.Favourites)
+	r.HandleFunc("/galleries/{id:[0-9]+}/like", likeGallery).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/images/{filename}/like", likeImage).Methods("POST")
+	r.HandleFunc("/favourites", favourites).Methods("GET")
+	statsCSV := requireUserMw.ApplyFn(galleriesC.StatsCSV)
+	r.HandleFunc("/galleries/{id:[0-9]+}/stats.csv", statsCSV).Methods("GET")
+	duplicateGallery := requireUserMw.ApplyFn(galleriesC.Duplicate)
+	r.HandleFunc("/galleries/{id:[0-9]+}/duplicate", duplicateGallery).Methods("POST")
+	// Ownership transfers
+	transfer := requireUserMw.ApplyFn(galleriesC.Transfer)
+	requestTransfer := requireUserMw.ApplyFn(galleriesC.RequestTransfer)
+	cancelTransfer := requireUserMw.ApplyFn(galleriesC.CancelTransfer)
+	confirmTransfer := requireUserMw.ApplyFn(galleriesC.ConfirmTransfer)
+	acceptTransfer := requireUserMw.ApplyFn(galleriesC.AcceptTransfer)
+	r.HandleFunc("/galleries/{id:[0-9]+}/transfer", transfer).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/transfer", requestTransfer).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/transfer/cancel", cancelTransfer).Methods("POST")
+	r.HandleFunc("/transfers/{token}", confirmTransfer).Methods("GET").Name(controllers.AcceptTransfer)
+	r.HandleFunc("/transfers/{token}", acceptTransfer).Methods("POST")
+	// Guest uploads
+	guestLinks := requireUserMw.ApplyFn(galleriesC.GuestLinks)
+	createGuestLink := requireUserMw.ApplyFn(galleriesC.CreateGuestLink)
+	revokeGuestLink := requireUserMw.ApplyFn(galleriesC.RevokeGuestLink)
+	guestUploadImage := requireUserMw.ApplyFn(galleriesC.GuestUploadImage)
+	approveGuestUpload := requireUserMw.ApplyFn(galleriesC.ApproveGuestUpload)
+	rejectGuestUpload := requireUserMw.ApplyFn(galleriesC.RejectGuestUpload)
+	approveAllGuestUploads := requireUserMw.ApplyFn(galleriesC.ApproveAllGuestUploads)
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests", guestLinks).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests", createGuestLink).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests/{lid:[0-9]+}/revoke", revokeGuestLink).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests/uploads/approve", approveAllGuestUploads).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests/uploads/{uid:[0-9]+}", guestUploadImage).Methods("GET")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests/uploads/{uid:[0-9]+}/approve", approveGuestUpload).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/guests/uploads/{uid:[0-9]+}/reject", rejectGuestUpload).Methods("POST")
+	r.HandleFunc("/g/{token}", galleriesC.GuestUploadForm).Methods("GET").Name(controllers.UploadAsGuest)
+	r.HandleFunc("/g/{token}", galleriesC.GuestUpload).Methods("POST")
+	// Trash
+	trash := requireUserMw.ApplyFn(galleriesC.Trash)
+	restoreGallery := requireUserMw.ApplyFn(galleriesC.Restore)
+	destroyGallery := requireUserMw.ApplyFn(galleriesC.DeleteForever)
+	r.HandleFunc("/galleries/trash", trash).Methods("GET").Name(controllers.TrashGalleries)
+	r.HandleFunc("/galleries/{id:[0-9]+}/restore", restoreGallery).Methods("POST")
+	r.HandleFunc("/galleries/{id:[0-9]+}/destroy", destroyGallery).Methods("POST")
+	// Collections
+	moveGallery := requireUserMw.ApplyFn(galleriesC.MoveGallery)
+	createCollection := requireUserMw.ApplyFn(collectionsC.Create)
+	updateCollection := requireUserMw.ApplyFn(collectionsC.Update)
+	deleteCollection := requireUserMw.ApplyFn(collectionsC.Delete)
+	r.HandleFunc("/galleries/{id:[0-9]+}/collection", moveGallery).Methods("POST")
+	r.HandleFunc("/collections", createCollection).Methods("POST")
+	r.HandleFunc("/collections/{id:[0-9]+}", collectionsC.Show).Methods("GET").Name(controllers.ShowCollection)
+	r.HandleFunc("/collections/{id:[0-9]+}/update", updateCollection).Methods("POST")
+	r.HandleFunc("/collections/{id:[0-9]+}/delete", deleteCollection).Methods("POST")
 	logout := requireUserMw.ApplyFn(usersC.Logout)
 	r.Handle("/logout", logout).Methods("POST")
 
+	// Accounts and public profiles
+	account := requireUserMw.ApplyFn(usersC.Account)
+	updateAccount := requireUserMw.ApplyFn(usersC.UpdateAccount)
+	uploadAvatar := requireUserMw.ApplyFn(usersC.UploadAvatar)
+	r.HandleFunc("/account", account).Methods("GET").Name(controllers.EditAccount)
+	r.HandleFunc("/account", updateAccount).Methods("POST")
+	r.HandleFunc("/account/avatar", uploadAvatar).Methods("POST")
+	r.HandleFunc("/u/{username}", usersC.Profile).Methods("GET").Name(controllers.ShowProfile)
+	// Following photographers
+	follow := requireUserMw.ApplyFn(usersC.Follow)
+	activity := requireUserMw.ApplyFn(usersC.Feed)
+	r.HandleFunc("/u/{username}/follow", follow).Methods("POST")
+	r.HandleFunc("/feed", activity).Methods("GET")
+	// Atom feeds of public galleries
+	r.HandleFunc("/feed.atom", feedsC.Site).Methods("GET")
+	r.HandleFunc("/sitemap.xml", feedsC.Sitemap).Methods("GET")
+	r.HandleFunc("/u/{username}/feed.atom", feedsC.User).Methods("GET").Name(controllers.UserFeed)
+	avatarHandler := http.FileServer(controllers.FilesOnly("./images/avatars/"))
+	avatarHandler = http.StripPrefix("/images/avatars/", avatarHandler)
+	r.PathPrefix("/images/avatars/").Handler(avatarHandler)
+
 	r.Handle("/forgot", usersC.ForgotPwView).Methods("GET")
 	r.HandleFunc("/forgot", usersC.InitiateReset).Methods("POST")
 	r.HandleFunc("/reset", usersC.ResetPw).Methods("GET")
@@ -0,0 +1,382 @@
+// Package markdown renders the subset of Markdown we accept in gallery descriptions to HTML that is safe to show to
+// anybody.
+//
+// Rather than rendering whatever HTML the Markdown produces and cleaning it up afterwards, the renderer never lets any
+// HTML through in the first place: every character of the input ends up HTML escaped, and the only markup in the
+// output is the handful of tags the renderer writes itself. Link targets are the one place user input ends up in an
+// attribute, so they are limited to http, https and mailto URLs and relative paths, and links get rel="nofollow
+// noopener noreferrer".
+//
+// Supported syntax:
+//
+//	# Headings (rendered from h3 down, since the page already has its own h1)
+//	**strong**, __strong__, *emphasis*, _emphasis_ and `code`
+//	[links](https://example.com) and <https://example.com>
+//	- unordered and 1. ordered lists
+//	> block quotes
+//	``` fenced code blocks ```
+//	--- horizontal rules
+//
+// Raw HTML is shown as text, and images are shown as links to the image. A line break inside a paragraph is kept as a
+// line break.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDepth limits how deeply block quotes and inline markup are nested, so that pathological input can't make us
+// recurse for ever. Anything nested deeper is rendered as text.
+const maxDepth = 8
+
+var (
+	headingRe    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	ruleRe       = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRe      = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	quoteRe      = regexp.MustCompile(`^ {0,3}> ?`)
+	unorderedRe  = regexp.MustCompile(`^ {0,3}[-*+][ \t]+`)
+	orderedRe    = regexp.MustCompile(`^ {0,3}([0-9]{1,9})[.)][ \t]+`)
+	continuation = regexp.MustCompile(`^(?: {2,}|\t)`)
+)
+
+// Render converts Markdown to HTML.
+func Render(src string) template.HTML {
+	src = strings.Replace(src, "\r\n", "\n", -1)
+	src = strings.Replace(src, "\r", "\n", -1)
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), 0)
+	return template.HTML(b.String())
+}
+
+// tagRe matches the tags Render writes. Everything else in its output is escaped, so there are no others.
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// PlainText converts Markdown to the text a reader would see, without any markup, for places that can't show HTML
+// like page descriptions. Links are reduced to their labels, and blocks are separated by line breaks.
+func PlainText(src string) string {
+	return html.UnescapeString(tagRe.ReplaceAllString(string(Render(src)), ""))
+}
+
+// renderBlocks renders lines as a sequence of block level elements.
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			fence := fenceRe.FindStringSubmatch(line)[1]
+			var code []string
+			i++
+			for i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				code = append(code, lines[i])
+				i++
+			}
+			// Skip the closing fence, if there is one
+			i++
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			level := len(m[1]) + 2
+			if level > 6 {
+				level = 6
+			}
+			tag := "h" + strconv.Itoa(level)
+			b.WriteString("<" + tag + ">")
+			b.WriteString(renderInline(m[2], depth, false))
+			b.WriteString("</" + tag + ">\n")
+			i++
+
+		case ruleRe.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+
+		case quoteRe.MatchString(line):
+			var quoted []string
+			for i < len(lines) && quoteRe.MatchString(lines[i]) {
+				quoted = append(quoted, quoteRe.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			if depth >= maxDepth {
+				renderParagraph(b, quoted, depth)
+				continue
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+
+		case unorderedRe.MatchString(line):
+			i = renderList(b, lines, i, unorderedRe, depth)
+
+		case orderedRe.MatchString(line):
+			i = renderList(b, lines, i, orderedRe, depth)
+
+		default:
+			start := i
+			i++
+			for i < len(lines) && !startsBlock(lines[i]) {
+				i++
+			}
+			renderParagraph(b, lines[start:i], depth)
+		}
+	}
+}
+
+// startsBlock reports whether a line ends the paragraph before it.
+func startsBlock(line string) bool {
+	return strings.TrimSpace(line) == "" ||
+		fenceRe.MatchString(line) ||
+		headingRe.MatchString(line) ||
+		ruleRe.MatchString(line) ||
+		quoteRe.MatchString(line) ||
+		unorderedRe.MatchString(line) ||
+		orderedRe.MatchString(line)
+}
+
+func renderParagraph(b *strings.Builder, lines []string, depth int) {
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	b.WriteString("<p>")
+	b.WriteString(renderInline(strings.Join(lines, "\n"), depth, false))
+	b.WriteString("</p>\n")
+}
+
+// renderList renders the list starting at lines[i] and returns the index of the first line after it. Each item is a
+// single paragraph of inline markup; lists can't be nested.
+func renderList(b *strings.Builder, lines []string, i int, marker *regexp.Regexp, depth int) int {
+	tag := "ul"
+	start := ""
+	if marker == orderedRe {
+		tag = "ol"
+		if n, _ := strconv.Atoi(marker.FindStringSubmatch(lines[i])[1]); n != 1 {
+			start = ` start="` + strconv.Itoa(n) + `"`
+		}
+	}
+	b.WriteString("<" + tag + start + ">\n")
+	for i < len(lines) && marker.MatchString(lines[i]) {
+		item := []string{marker.ReplaceAllString(lines[i], "")}
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" && continuation.MatchString(lines[i]) {
+			item = append(item, strings.TrimSpace(lines[i]))
+			i++
+		}
+		b.WriteString("<li>")
+		b.WriteString(renderInline(strings.Join(item, "\n"), depth, false))
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderInline renders emphasis, code spans and links, escaping everything else. inLink stops links from being
+// nested inside each other.
+func renderInline(s string, depth int, inLink bool) string {
+	if depth >= maxDepth {
+		return html.EscapeString(s)
+	}
+	var b strings.Builder
+	// text is the start of the plain text we haven't written yet
+	text := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(s[text:end]))
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			flush(i)
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			text = i
+			continue
+
+		case c == '\n':
+			flush(i)
+			b.WriteString("<br>\n")
+			i++
+			text = i
+			continue
+
+		case c == '`':
+			n := runLength(s, i, '`')
+			if end := strings.Index(s[i+n:], s[i:i+n]); end >= 0 {
+				flush(i)
+				code := s[i+n : i+n+end]
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(strings.TrimSpace(code)))
+				b.WriteString("</code>")
+				i += n + end + n
+				text = i
+				continue
+			}
+			// An unmatched run of backticks is just text
+			i += n
+			continue
+
+		case c == '[' && !inLink:
+			if label, href, end, ok := parseLink(s, i); ok {
+				flush(i)
+				writeLink(&b, href, renderInline(label, depth+1, true))
+				i = end
+				text = i
+				continue
+			}
+
+		case c == '<' && !inLink:
+			if end := strings.IndexByte(s[i:], '>'); end > 0 {
+				href := s[i+1 : i+end]
+				if !strings.ContainsAny(href, " \t\n<") && strings.Contains(href, ":") && safeURL(href) {
+					flush(i)
+					writeLink(&b, href, html.EscapeString(href))
+					i += end + 1
+					text = i
+					continue
+				}
+			}
+
+		case c == '*' || c == '_':
+			if inner, end, strong, ok := parseEmphasis(s, i); ok {
+				flush(i)
+				tag := "em"
+				if strong {
+					tag = "strong"
+				}
+				b.WriteString("<" + tag + ">")
+				b.WriteString(renderInline(inner, depth+1, inLink))
+				b.WriteString("</" + tag + ">")
+				i = end
+				text = i
+				continue
+			}
+			// Skip the whole run so that eg the second * of ** isn't tried on its own
+			i += runLength(s, i, c)
+			continue
+		}
+		i++
+	}
+	flush(len(s))
+	return b.String()
+}
+
+// parseLink parses a [label](href) link starting at s[i]. It returns the label, the href and the index just past the
+// link. Links with an unsafe href are left alone and shown as text.
+func parseLink(s string, i int) (label, href string, end int, ok bool) {
+	nesting := 0
+	closing := -1
+	for j := i + 1; j < len(s) && closing < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			nesting++
+		case ']':
+			if nesting == 0 {
+				closing = j
+			}
+			nesting--
+		}
+	}
+	if closing < 0 || closing+1 >= len(s) || s[closing+1] != '(' {
+		return "", "", 0, false
+	}
+	paren := strings.IndexByte(s[closing+2:], ')')
+	if paren < 0 {
+		return "", "", 0, false
+	}
+	// Anything after the URL, like a title, is ignored
+	fields := strings.Fields(s[closing+2 : closing+2+paren])
+	if len(fields) == 0 || !safeURL(fields[0]) {
+		return "", "", 0, false
+	}
+	return s[i+1 : closing], fields[0], closing + 2 + paren + 1, true
+}
+
+// parseEmphasis parses emphasis starting at s[i], which is either * or _. Two delimiters make strong emphasis. As in
+// most Markdown, the text has to hug the delimiters, and underscores inside words (like snake_case) don't count.
+func parseEmphasis(s string, i int) (inner string, end int, strong, ok bool) {
+	c := s[i]
+	n := runLength(s, i, c)
+	if n > 2 {
+		return "", 0, false, false
+	}
+	open := i + n
+	if open >= len(s) || isSpace(s[open]) {
+		return "", 0, false, false
+	}
+	if c == '_' && i > 0 && isAlnum(s[i-1]) {
+		return "", 0, false, false
+	}
+	for j := open + 1; j < len(s); j++ {
+		if s[j] == '\\' {
+			j++
+			continue
+		}
+		if s[j] != c || runLength(s, j, c) != n || s[j-1] == c || isSpace(s[j-1]) {
+			continue
+		}
+		after := j + n
+		if c == '_' && after < len(s) && isAlnum(s[after]) {
+			continue
+		}
+		return s[open:j], after, n == 2, true
+	}
+	return "", 0, false, false
+}
+
+func writeLink(b *strings.Builder, href, label string) {
+	b.WriteString(`<a href="`)
+	b.WriteString(html.EscapeString(href))
+	b.WriteString(`" rel="nofollow noopener noreferrer">`)
+	b.WriteString(label)
+	b.WriteString("</a>")
+}
+
+// safeURL reports whether a link may point at the URL. Only http, https and mailto URLs and relative paths are allowed,
+// which rules out javascript:, data: and the like however they are written.
+func safeURL(href string) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	case "":
+		// A relative path. url.Parse would have found a scheme, or refused a colon before the first slash, so there
+		// is no way to sneak one in here.
+		return true
+	}
+	return false
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,222 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	// outputTagRe matches every tag in the output. Escaped text never contains a <, so anything that looks like the
+	// start of a tag has to be one of these.
+	outputTagRe = regexp.MustCompile(`<(/?)([a-z0-9]+)((?: [a-z]+="[^"]*")*)>`)
+	attrRe      = regexp.MustCompile(` ([a-z]+)="([^"]*)"`)
+	// schemeRe matches the scheme of a URL the way a browser finds it, once it has dropped any tabs and newlines.
+	schemeRe = regexp.MustCompile(`^[\x00-\x20]*([a-zA-Z][a-zA-Z0-9+.-]*):`)
+
+	allowedTags = map[string]bool{
+		"p": true, "br": true, "hr": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"strong": true, "em": true, "code": true, "pre": true, "a": true,
+		"ul": true, "ol": true, "li": true, "blockquote": true,
+	}
+)
+
+// checkSafe fails the test if the output contains any tag or attribute other than the ones the renderer writes
+// itself, or a link to anything but the URLs safeURL allows.
+func checkSafe(t *testing.T, src string, out string) {
+	t.Helper()
+	rest := outputTagRe.ReplaceAllStringFunc(out, func(tag string) string {
+		m := outputTagRe.FindStringSubmatch(tag)
+		if !allowedTags[m[2]] {
+			t.Errorf("Render(%q) wrote a <%s> tag:\n%s", src, m[2], out)
+		}
+		for _, attr := range attrRe.FindAllStringSubmatch(m[3], -1) {
+			name, value := attr[1], html.UnescapeString(attr[2])
+			switch {
+			case m[2] == "a" && name == "href":
+				stripped := strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(value)
+				if scheme := schemeRe.FindStringSubmatch(stripped); scheme != nil {
+					switch strings.ToLower(scheme[1]) {
+					case "http", "https", "mailto":
+					default:
+						t.Errorf("Render(%q) linked to %q:\n%s", src, value, out)
+					}
+				}
+			case m[2] == "a" && name == "rel" && value == "nofollow noopener noreferrer":
+			case m[2] == "ol" && name == "start":
+			default:
+				t.Errorf("Render(%q) wrote a %s attribute on <%s>:\n%s", src, name, m[2], out)
+			}
+		}
+		return ""
+	})
+	if strings.ContainsAny(rest, `<>"`) {
+		t.Errorf("Render(%q) left unescaped markup behind:\n%s", src, out)
+	}
+}
+
+func TestRenderXSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		// want must appear in the output
+		want string
+	}{
+		{"script tag", `<script>alert(1)</script>`, "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"img onerror", `<img src=x onerror="alert(1)">`, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+		{"script in emphasis", `*<script>alert(1)</script>*`, "<em>&lt;script&gt;"},
+		{"javascript link", `[x](javascript:alert(1))`, "[x](javascript:alert(1))"},
+		{"mixed case javascript link", `[x](JaVaScRiPt:alert(1))`, "[x](JaVaScRiPt:alert(1))"},
+		{"javascript link with spaces", `[x]( javascript:alert(1) )`, "[x]( javascript:alert(1) )"},
+		// Entities in a link are written out escaped, so the browser never decodes them into a scheme
+		{"entity encoded link", `[x](jav&#x61;script:alert(1))`, "jav&amp;#x61;script"},
+		{"entity encoded colon", `[x](javascript&colon;alert(1))`, "javascript&amp;colon;alert(1"},
+		{"javascript autolink", `<javascript:alert(1)>`, "&lt;javascript:alert(1)&gt;"},
+		{"mixed case autolink", `<JaVaScRiPt:alert(1)>`, "&lt;JaVaScRiPt:alert(1)&gt;"},
+		{"entity encoded autolink", `<jav&#x61;script:alert(1)>`, "jav&amp;#x61;script:alert(1)"},
+		{"decimal entity autolink", `<&#106;avascript:alert(1)>`, "&amp;#106;avascript:alert(1)"},
+		{"data link", `[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)`, "[x](data:text/html"},
+		{"data autolink", `<data:text/html,<script>alert(1)</script>>`, "&lt;data:text/html,&lt;script&gt;"},
+		{"vbscript link", `[x](vbscript:msgbox(1))`, "[x](vbscript:msgbox(1))"},
+		{"vbscript autolink", `<VBScript:msgbox(1)>`, "&lt;VBScript:msgbox(1)&gt;"},
+		{"image", `![x](javascript:alert(1))`, "![x](javascript:alert(1))"},
+		{"quote in link", `[x](https://example.com/"onmouseover="alert(1))`,
+			`href="https://example.com/&#34;onmouseover=&#34;alert(1"`},
+		{"quote in autolink", `<https://example.com/"onmouseover="alert(1)>`,
+			`href="https://example.com/&#34;onmouseover=&#34;alert(1)"`},
+		{"quote in label", `[x" onclick="alert(1)](https://example.com)`, "x&#34; onclick=&#34;alert(1)</a>"},
+		{"html in link label", `[<b onclick=alert(1)>x</b>](https://example.com)`, "&lt;b onclick=alert(1)&gt;x"},
+		{"html in code span", "`<script>alert(1)</script>`", "<code>&lt;script&gt;alert(1)&lt;/script&gt;</code>"},
+		{"html in fenced code", "```html\n<script>alert(1)</script>\n<img src=x onerror=alert(1)>\n```",
+			"<pre><code>&lt;script&gt;alert(1)&lt;/script&gt;\n&lt;img src=x onerror=alert(1)&gt;</code></pre>"},
+		{"unclosed fence", "~~~\n<script>alert(1)</script>", "<pre><code>&lt;script&gt;"},
+		{"html in heading", `# <script>alert(1)</script>`, "<h3>&lt;script&gt;"},
+		{"html in list", "- <script>alert(1)</script>", "<li>&lt;script&gt;"},
+		{"html in quote", "> <script>alert(1)</script>", "<blockquote>\n<p>&lt;script&gt;"},
+		{"escaped bracket", `\<script>alert(1)\</script>`, "&lt;script&gt;"},
+		{"safe link", `[x](https://example.com/a?b=1&c=2)`,
+			`<a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">x</a>`},
+		{"relative link", `[x](/galleries/1)`, `<a href="/galleries/1" rel="nofollow noopener noreferrer">x</a>`},
+		{"mailto autolink", `<mailto:me@example.com>`, `<a href="mailto:me@example.com"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := string(Render(test.src))
+			checkSafe(t, test.src, out)
+			if !strings.Contains(out, test.want) {
+				t.Errorf("Render(%q) = %q, want it to contain %q", test.src, out, test.want)
+			}
+		})
+	}
+}
+
+func TestRenderUnsafeURLsAreNotLinked(t *testing.T) {
+	for _, href := range []string{
+		"javascript:alert(1)",
+		"JAVASCRIPT:alert(1)",
+		"java\tscript:alert(1)",
+		"data:text/html,x",
+		"vbscript:msgbox(1)",
+		"http:///x",
+		"mailto:",
+	} {
+		if safeURL(href) {
+			t.Errorf("safeURL(%q) = true, want false", href)
+		}
+		for _, src := range []string{"[x](" + href + ")", "<" + href + ">"} {
+			out := string(Render(src))
+			checkSafe(t, src, out)
+		}
+	}
+}
+
+func TestRenderNesting(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"unclosed strong", "**a **b **c"},
+		{"unclosed emphasis", "*a _b *c _d"},
+		{"unclosed link", "[[[[[[x](https://example.com"},
+		{"unclosed code", "``a `b"},
+		{"unclosed autolink", "<https://example.com"},
+		{"stars", strings.Repeat("*", 10000)},
+		{"underscores", strings.Repeat("_", 10000)},
+		{"nested emphasis", strings.Repeat("*a _b ", 1000) + "c" + strings.Repeat(" b_ a*", 1000)},
+		{"nested strong", strings.Repeat("**a __b ", 1000) + "c" + strings.Repeat(" b__ a**", 1000)},
+		{"nested quotes", strings.Repeat(">", 1000) + " *x*"},
+		{"nested quotes with spaces", strings.Repeat("> ", 1000) + "x"},
+		{"nested links", strings.Repeat("[", 1000) + "x" + strings.Repeat("](https://example.com)", 1000)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := string(Render(test.src))
+			checkSafe(t, test.src, out)
+			if n := strings.Count(out, "<blockquote>"); n > maxDepth {
+				t.Errorf("Render(%q) nested %d block quotes, want at most %d", test.src, n, maxDepth)
+			}
+			if n := strings.Count(out, "<a "); n > 1 {
+				t.Errorf("Render(%q) wrote %d links, want links not to nest", test.src, n)
+			}
+			for _, tag := range []string{"blockquote", "strong", "em", "a", "code", "p"} {
+				open := len(regexp.MustCompile("<"+tag+"[ >]").FindAllString(out, -1))
+				if closed := strings.Count(out, "</"+tag+">"); open != closed {
+					t.Errorf("Render(%q) opened %d <%s> tags and closed %d:\n%s", test.src, open, tag, closed, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderInlineMaxDepth(t *testing.T) {
+	if got, want := renderInline("**a** <b>", maxDepth, false), "**a** &lt;b&gt;"; got != want {
+		t.Errorf("renderInline at maxDepth = %q, want %q", got, want)
+	}
+	if got, want := renderInline("**a**", maxDepth-1, false), "<strong>a</strong>"; got != want {
+		t.Errorf("renderInline below maxDepth = %q, want %q", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"Hello *there*", "<p>Hello <em>there</em></p>\n"},
+		{"**bold** and __bold__", "<p><strong>bold</strong> and <strong>bold</strong></p>\n"},
+		{"snake_case_name", "<p>snake_case_name</p>\n"},
+		{"# Title", "<h3>Title</h3>\n"},
+		{"###### Small", "<h6>Small</h6>\n"},
+		{"a\nb", "<p>a<br>\nb</p>\n"},
+		{"- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"---", "<hr>\n"},
+		{"> quoted", "<blockquote>\n<p>quoted</p>\n</blockquote>\n"},
+		{"`a < b`", "<p><code>a &lt; b</code></p>\n"},
+		{"Tom & Jerry", "<p>Tom &amp; Jerry</p>\n"},
+	}
+	for _, test := range tests {
+		if got := string(Render(test.src)); got != test.want {
+			t.Errorf("Render(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"**Rome** in _spring_", "Rome in spring\n"},
+		{"# Day one\nWe walked to [the Colosseum](https://example.com).", "Day one\nWe walked to the Colosseum.\n"},
+		{"```\ncode & <b>\n```", "code & <b>\n"},
+		{"- one\n- two", "\none\ntwo\n\n"},
+		{"Tom &amp; Jerry", "Tom &amp; Jerry\n"},
+	}
+	for _, test := range tests {
+		if got := PlainText(test.src); got != test.want {
+			t.Errorf("PlainText(%q) = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Roles a user can have on a gallery. The owner is the user the gallery belongs to; every other role is granted by
+// the owner through a Collaborator.
+const (
+	RoleOwner       = "owner"
+	RoleEditor      = "editor"
+	RoleContributor = "contributor"
+	RoleViewer      = "viewer"
+)
+
+// Permission is something a user may be allowed to do with a gallery.
+type Permission int
+
+const (
+	// PermView lets a user see the gallery and its images
+	PermView Permission = iota
+	// PermUpload lets a user add images
+	PermUpload
+	// PermEdit lets a user rename the gallery and delete images
+	PermEdit
+	// PermManage lets a user change the gallery's settings, share it, and delete it
+	PermManage
+)
+
+// RoleAllows reports whether the role grants the permission. Each role can do everything the role below it can.
+func RoleAllows(role string, perm Permission) bool {
+	switch role {
+	case RoleOwner:
+		return true
+	case RoleEditor:
+		return perm <= PermEdit
+	case RoleContributor:
+		return perm <= PermUpload
+	case RoleViewer:
+		return perm <= PermView
+	}
+	return false
+}
+
+// Collaborator is an invitation for someone to work on a gallery with a given role. The invitation is sent to Email,
+// and UserID is filled in when a user accepts it with the token from that email.
+type Collaborator struct {
+	gorm.Model
+	GalleryID  uint   `gorm:"not null;index"`
+	UserID     uint   `gorm:"index"`
+	Email      string `gorm:"not null"`
+	Role       string `gorm:"not null"`
+	Token      string `gorm:"-"`
+	TokenHash  string `gorm:"not null;unique_index"`
+	AcceptedAt *time.Time
+}
+
+// Accepted reports whether a user has accepted the invitation.
+func (c *Collaborator) Accepted() bool {
+	return c.AcceptedAt != nil
+}
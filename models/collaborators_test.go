@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestRoleAllows(t *testing.T) {
+	perms := []Permission{PermView, PermUpload, PermEdit, PermManage}
+	tests := []struct {
+		role string
+		// want[i] is whether the role allows perms[i]
+		want []bool
+	}{
+		{RoleOwner, []bool{true, true, true, true}},
+		{RoleEditor, []bool{true, true, true, false}},
+		{RoleContributor, []bool{true, true, false, false}},
+		{RoleViewer, []bool{true, false, false, false}},
+		{"", []bool{false, false, false, false}},
+		{"admin", []bool{false, false, false, false}},
+	}
+	for _, test := range tests {
+		for i, perm := range perms {
+			if got := RoleAllows(test.role, perm); got != test.want[i] {
+				t.Errorf("RoleAllows(%q, %d) = %v, want %v", test.role, perm, got, test.want[i])
+			}
+		}
+	}
+}
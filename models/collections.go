@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Collection groups galleries, and other collections, into albums like Client → Event → Gallery. A collection with no
+// ParentID is at the top level.
+//
+// A collection can only ever make what is inside it harder to see: the visibility of a gallery or collection is the
+// most restrictive of its own and that of every collection above it. InheritedVisibility holds the most restrictive
+// visibility of the collections above this one and is filled in when a collection is loaded.
+type Collection struct {
+	gorm.Model
+	UserID              uint   `gorm:"not null;index"`
+	ParentID            *uint  `gorm:"index"`
+	Title               string `gorm:"not null"`
+	Visibility          string `gorm:"not null;default:'private'"`
+	InheritedVisibility string `gorm:"-"`
+	Depth               int    `gorm:"-"`
+}
+
+// EffectiveVisibility is the visibility that actually applies to the collection once its parents are taken into
+// account.
+func (c *Collection) EffectiveVisibility() string {
+	return effectiveVisibility(c.Visibility, c.InheritedVisibility)
+}
+
+// IsPrivate reports whether only the owner may see the collection.
+func (c *Collection) IsPrivate() bool {
+	return c.EffectiveVisibility() == VisibilityPrivate
+}
+
+// IsChildOf reports whether the collection is directly inside the collection with the given ID.
+func (c *Collection) IsChildOf(collectionID uint) bool {
+	return c.ParentID != nil && *c.ParentID == collectionID
+}
+
+// IndentedTitle is the title indented by the collection's Depth, for showing a tree of collections in a select box.
+func (c *Collection) IndentedTitle() string {
+	return strings.Repeat("\u2014 ", c.Depth) + c.Title
+}
+
+// visibilityRanks orders visibility levels from the most to the least restrictive.
+var visibilityRanks = map[string]int{
+	VisibilityPrivate:  0,
+	VisibilityUnlisted: 1,
+	VisibilityPublic:   2,
+}
+
+// MostRestrictive returns whichever of the given visibility levels lets the fewest people in. Anything unrecognised,
+// including an empty string, is treated as private.
+func MostRestrictive(visibilities ...string) string {
+	ret := VisibilityPublic
+	for _, v := range visibilities {
+		rank, ok := visibilityRanks[v]
+		if !ok {
+			return VisibilityPrivate
+		}
+		if rank < visibilityRanks[ret] {
+			ret = v
+		}
+	}
+	return ret
+}
+
+// effectiveVisibility combines something's own visibility with the visibility it inherits, if it inherits one.
+func effectiveVisibility(own, inherited string) string {
+	if inherited == "" {
+		return MostRestrictive(own)
+	}
+	return MostRestrictive(own, inherited)
+}
+
+// AtLeastAsOpen reports whether visibility a lets in everybody that visibility b does.
+func AtLeastAsOpen(a, b string) bool {
+	return MostRestrictive(a, b) == b
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMostRestrictive(t *testing.T) {
+	tests := []struct {
+		visibilities []string
+		want         string
+	}{
+		{nil, VisibilityPublic},
+		{[]string{VisibilityPublic}, VisibilityPublic},
+		{[]string{VisibilityUnlisted, VisibilityPublic}, VisibilityUnlisted},
+		{[]string{VisibilityPublic, VisibilityPrivate, VisibilityUnlisted}, VisibilityPrivate},
+		{[]string{""}, VisibilityPrivate},
+		{[]string{VisibilityPublic, "secret"}, VisibilityPrivate},
+	}
+	for _, test := range tests {
+		if got := MostRestrictive(test.visibilities...); got != test.want {
+			t.Errorf("MostRestrictive(%q) = %q, want %q", test.visibilities, got, test.want)
+		}
+	}
+}
+
+func TestAtLeastAsOpen(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{VisibilityPublic, VisibilityPrivate, true},
+		{VisibilityUnlisted, VisibilityUnlisted, true},
+		{VisibilityPrivate, VisibilityUnlisted, false},
+		{VisibilityUnlisted, VisibilityPublic, false},
+	}
+	for _, test := range tests {
+		if got := AtLeastAsOpen(test.a, test.b); got != test.want {
+			t.Errorf("AtLeastAsOpen(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestEffectiveVisibility(t *testing.T) {
+	tests := []struct {
+		own, inherited string
+		want           string
+	}{
+		{VisibilityPublic, "", VisibilityPublic},
+		{VisibilityUnlisted, "", VisibilityUnlisted},
+		{VisibilityPublic, VisibilityUnlisted, VisibilityUnlisted},
+		{VisibilityPublic, VisibilityPrivate, VisibilityPrivate},
+		// A collection can only ever make what is inside it harder to see
+		{VisibilityPrivate, VisibilityPublic, VisibilityPrivate},
+		{VisibilityUnlisted, VisibilityPublic, VisibilityUnlisted},
+		{"", "", VisibilityPrivate},
+	}
+	for _, test := range tests {
+		c := Collection{Visibility: test.own, InheritedVisibility: test.inherited}
+		if got := c.EffectiveVisibility(); got != test.want {
+			t.Errorf("collection %q inside %q has visibility %q, want %q", test.own, test.inherited, got, test.want)
+		}
+		g := Gallery{Visibility: test.own, InheritedVisibility: test.inherited}
+		if got := g.EffectiveVisibility(); got != test.want {
+			t.Errorf("gallery %q inside %q has visibility %q, want %q", test.own, test.inherited, got, test.want)
+		}
+	}
+}
+
+func TestGalleryEffectiveVisibilitySchedule(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	tests := []struct {
+		name      string
+		publishAt *time.Time
+		expireAt  *time.Time
+		want      string
+	}{
+		{"unscheduled", nil, nil, VisibilityPublic},
+		{"published", &past, nil, VisibilityPublic},
+		{"scheduled", &future, nil, VisibilityPrivate},
+		{"expiring", nil, &future, VisibilityPublic},
+		{"expired", &past, &past, VisibilityPrivate},
+	}
+	for _, test := range tests {
+		g := Gallery{Visibility: VisibilityPublic, PublishAt: test.publishAt, ExpireAt: test.expireAt}
+		if got := g.EffectiveVisibility(); got != test.want {
+			t.Errorf("%s gallery has visibility %q, want %q", test.name, got, test.want)
+		}
+		if got := g.LiveVisibility(); got != VisibilityPublic {
+			t.Errorf("%s gallery has live visibility %q, want %q", test.name, got, VisibilityPublic)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Comment is left by a visitor on a gallery, or on a single image in it when Filename is set. Visitors don't need an
+// account to comment, so UserID is only set for signed in users and Name is whatever the commenter gave.
+//
+// Comments on galleries with CommentsModerated aren't shown until the owner approves them.
+type Comment struct {
+	gorm.Model
+	GalleryID uint   `gorm:"not null;index"`
+	Filename  string `gorm:"not null;default:''"`
+	UserID    *uint
+	Name      string `gorm:"not null"`
+	Body      string `gorm:"type:text;not null"`
+	Approved  bool   `gorm:"not null;default:false"`
+}
+
+// OnImage reports whether the comment is about a single image rather than the whole gallery.
+func (c *Comment) OnImage() bool {
+	return c.Filename != ""
+}
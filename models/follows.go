@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Follow is a user following another user, so that the followee's public galleries show up in the follower's feed.
+// Unfollowing deletes the follow for good.
+type Follow struct {
+	gorm.Model
+	FollowerID uint `gorm:"not null;index"`
+	FolloweeID uint `gorm:"not null;index"`
+}
+
+// FeedItem is something that happened in one of the public galleries of a user being followed: either the gallery
+// was published, or images were added to it. Images added on the same day are grouped into one item, which holds the
+// most recent of them.
+type FeedItem struct {
+	Gallery *Gallery
+	// Count is how many images were added, or 0 when the gallery was published
+	Count  int
+	Images []Image
+	At     time.Time
+}
+
+// Published reports whether the item is for the gallery being published rather than images being added to it.
+func (i *FeedItem) Published() bool {
+	return i.Count == 0
+}
+
+// FeedQuery describes one page of a user's feed. Like GalleryQuery, pages are found with keyset pagination: After and
+// Before are opaque cursors taken from a previous FeedPage, and at most one of them should be set.
+type FeedQuery struct {
+	UserID uint
+	After  string
+	Before string
+	Limit  int
+}
+
+// FeedPage is a page of a feed, newest first, along with the cursors for the pages either side of it. A cursor is
+// empty when there is no page in that direction.
+type FeedPage struct {
+	Items      []FeedItem
+	PrevCursor string
+	NextCursor string
+}
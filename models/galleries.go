@@ -1,15 +1,205 @@
 package models
 
 import (
+	"html/template"
+	"strings"
+	"time"
+
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
+)
+
+// Gallery visibility levels. Private galleries are only visible to their owner, unlisted galleries are visible to
+// anyone with the link, and public galleries may also be listed elsewhere on the site.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+// Comment moderation modes. With CommentsModerated, comments only appear once the owner has approved them.
+const (
+	CommentsOff       = "off"
+	CommentsModerated = "moderated"
+	CommentsOpen      = "open"
 )
 
+// Gallery is a set of images belonging to a user. InheritedVisibility is the most restrictive visibility of the
+// collections the gallery is in (see Collection), and is filled in when the gallery is loaded.
+//
+// A gallery can be scheduled: it is private until PublishAt, and expires at ExpireAt. If NotifyExpiry is set the owner
+// is emailed a week before it expires, and ExpiryNotifiedAt records that we have done so.
 type Gallery struct {
 	gorm.Model
-	UserID uint    `gorm:"not_null;index"`
-	Title  string  `gorm:"not_null"`
-	Images []Image `gorm:"-"`
+	UserID       uint   `gorm:"not_null;index"`
+	CollectionID *uint  `gorm:"index"`
+	Title        string `gorm:"not_null"`
+	Slug         string `gorm:"not null;default:''"`
+	Description  string `gorm:"type:text"`
+	Proofing     bool   `gorm:"not null;default:false"`
+	Visibility   string `gorm:"not null;default:'private'"`
+	CommentMode  string `gorm:"not null;default:'off'"`
+	Layout       string `gorm:"not null;default:'grid'"`
+	Columns      int    `gorm:"not null;default:0"`
+	Password     string `gorm:"-"`
+	PasswordHash string
+	Tags         pq.StringArray `gorm:"type:text[]"`
+	ImageCount   int            `gorm:"not null;default:0"`
+	Images       []Image        `gorm:"-"`
+
+	PublishAt        *time.Time `gorm:"index"`
+	ExpireAt         *time.Time `gorm:"index"`
+	NotifyExpiry     bool       `gorm:"not null;default:false"`
+	ExpiryNotifiedAt *time.Time
+
+	InheritedVisibility string `gorm:"-"`
+}
+
+// maxSlugLength is the longest slug Slugify will make.
+const maxSlugLength = 60
+
+// Slugify turns a gallery title into the part of its URL that identifies it among its owner's galleries, e.g.
+// "Summer in Rome!" becomes "summer-in-rome". Anything other than ASCII letters and digits becomes a dash, so titles
+// with nothing else in them give an empty slug.
+func Slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			// A dash is only written along with the character after it, so the slug never ends with one
+			n := 1
+			if dash && b.Len() > 0 {
+				n = 2
+			}
+			if b.Len()+n > maxSlugLength {
+				break
+			}
+			if n == 2 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// CommentsEnabled reports whether visitors may comment on the gallery.
+func (g *Gallery) CommentsEnabled() bool {
+	return g.CommentMode == CommentsModerated || g.CommentMode == CommentsOpen
+}
+
+// TagList returns the gallery's tags the way they are entered in a form.
+func (g *Gallery) TagList() string {
+	return strings.Join(g.Tags, ", ")
+}
+
+// HasPassword reports whether visitors need to unlock the gallery with a password before they can see it.
+func (g *Gallery) HasPassword() bool {
+	return g.PasswordHash != ""
+}
+
+// EffectiveVisibility is the visibility that actually applies to the gallery once the collections it is in and its
+// schedule are taken into account.
+func (g *Gallery) EffectiveVisibility() string {
+	if g.Scheduled() || g.Expired() {
+		return VisibilityPrivate
+	}
+	return g.LiveVisibility()
+}
+
+// LiveVisibility is the visibility the gallery has while it is published, taking its collections into account but
+// not its schedule.
+func (g *Gallery) LiveVisibility() string {
+	return effectiveVisibility(g.Visibility, g.InheritedVisibility)
+}
+
+// Scheduled reports whether the gallery has a publish time that hasn't come yet.
+func (g *Gallery) Scheduled() bool {
+	return g.PublishAt != nil && time.Now().Before(*g.PublishAt)
+}
+
+// Expired reports whether the gallery's expiry time has passed.
+func (g *Gallery) Expired() bool {
+	return g.ExpireAt != nil && !time.Now().Before(*g.ExpireAt)
+}
+
+// ChangedAt returns when the gallery last changed for its visitors. That is when it was last updated, or when it was
+// published if that came later, since the row isn't touched when a scheduled gallery's publish time passes.
+func (g *Gallery) ChangedAt() time.Time {
+	if g.PublishAt != nil && !g.Scheduled() && g.PublishAt.After(g.UpdatedAt) {
+		return *g.PublishAt
+	}
+	return g.UpdatedAt
+}
+
+// ScheduleLayout is how publish and expiry times are written in forms. It matches what a datetime-local input sends.
+const ScheduleLayout = "2006-01-02T15:04"
+
+// PublishAtInput returns the gallery's publish time the way it is entered in a form.
+func (g *Gallery) PublishAtInput() string {
+	return scheduleInput(g.PublishAt)
+}
+
+// ExpireAtInput returns the gallery's expiry time the way it is entered in a form.
+func (g *Gallery) ExpireAtInput() string {
+	return scheduleInput(g.ExpireAt)
+}
+
+func scheduleInput(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(ScheduleLayout)
+}
+
+// IsPrivate reports whether only the owner may see the gallery.
+func (g *Gallery) IsPrivate() bool {
+	return g.EffectiveVisibility() == VisibilityPrivate
+}
+
+// IsPublic reports whether the gallery may be listed for anyone to find.
+func (g *Gallery) IsPublic() bool {
+	return g.EffectiveVisibility() == VisibilityPublic
+}
+
+// InCollection reports whether the gallery is directly inside the collection with the given ID.
+func (g *Gallery) InCollection(collectionID uint) bool {
+	return g.CollectionID != nil && *g.CollectionID == collectionID
+}
+
+// Cover is the image shown for the gallery in lists of galleries, or nil if it has no images (or they weren't loaded).
+func (g *Gallery) Cover() *Image {
+	if len(g.Images) == 0 {
+		return nil
+	}
+	return &g.Images[0]
+}
+
+// Duplicate returns a new, unsaved gallery with the same settings and tags as this one. Its title gets a " (copy)"
+// suffix so the two can be told apart. Images aren't part of the copy; they have to be copied separately.
+func (g *Gallery) Duplicate() Gallery {
+	tags := make(pq.StringArray, len(g.Tags))
+	copy(tags, g.Tags)
+	return Gallery{
+		UserID:       g.UserID,
+		CollectionID: g.CollectionID,
+		Title:        g.Title + " (copy)",
+		Description:  g.Description,
+		Proofing:     g.Proofing,
+		Visibility:   g.Visibility,
+		CommentMode:  g.CommentMode,
+		Layout:       g.Layout,
+		Columns:      g.Columns,
+		PasswordHash: g.PasswordHash,
+		Tags:         tags,
+		PublishAt:    g.PublishAt,
+		ExpireAt:     g.ExpireAt,
+		NotifyExpiry: g.NotifyExpiry,
+	}
 }
 
 func (g *Gallery) ImagesSplitN(n int) [][]Image {
@@ -36,3 +226,77 @@ func (g *Gallery) ImagesSplitN(n int) [][]Image {
 	}
 	return ret
 }
+
+// Ways the galleries index can be sorted
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortTitle   = "title"
+	SortImages  = "images"
+)
+
+// GalleryQuery describes one page of a user's galleries. Pages are found with keyset pagination: After and Before
+// are opaque cursors taken from a previous GalleryPage, and at most one of them should be set.
+type GalleryQuery struct {
+	UserID     uint
+	Sort       string
+	Desc       bool
+	Visibility string
+	Tag        string
+	After      string
+	Before     string
+	Limit      int
+}
+
+// GalleryPage is a page of galleries, along with the cursors for the pages either side of it. A cursor is empty when
+// there is no page in that direction.
+type GalleryPage struct {
+	Galleries  []Gallery
+	PrevCursor string
+	NextCursor string
+}
+
+// Markers ts_headline wraps around matching words. They are replaced with <mark> tags once the rest of the headline
+// has been escaped. They are control characters, which don't turn up in the titles, descriptions and captions people
+// write the way bracketed markers could.
+const (
+	HighlightStart = "\x01"
+	HighlightStop  = "\x02"
+)
+
+// GallerySearchResult is a gallery that matched a search, along with how well it matched and an excerpt of the
+// matching text.
+type GallerySearchResult struct {
+	Gallery
+	Rank     float64
+	Headline string
+}
+
+// Highlight returns the headline as HTML with matching words wrapped in <mark> tags. Everything else in the headline
+// is user input, so it is escaped first. Markers that don't open or close a highlight are dropped, so the tags are
+// always balanced.
+func (r *GallerySearchResult) Highlight() template.HTML {
+	var b strings.Builder
+	marked := false
+	rest := r.Headline
+	for {
+		i := strings.IndexAny(rest, HighlightStart+HighlightStop)
+		if i < 0 {
+			break
+		}
+		b.WriteString(template.HTMLEscapeString(rest[:i]))
+		start := rest[i:i+1] == HighlightStart
+		if start && !marked {
+			b.WriteString("<mark>")
+		} else if !start && marked {
+			b.WriteString("</mark>")
+		}
+		marked = start
+		rest = rest[i+1:]
+	}
+	b.WriteString(template.HTMLEscapeString(rest))
+	if marked {
+		b.WriteString("</mark>")
+	}
+	return template.HTML(b.String())
+}
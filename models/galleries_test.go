@@ -0,0 +1,54 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Summer in Rome!", "summer-in-rome"},
+		{"  Leading and trailing  ", "leading-and-trailing"},
+		{"Rome -- 2021 / Day 3", "rome-2021-day-3"},
+		{"CAPS", "caps"},
+		{"Café au lait", "caf-au-lait"},
+		{"日本", ""},
+		{"!!!", ""},
+		{"", ""},
+		{strings.Repeat("a", 100), strings.Repeat("a", maxSlugLength)},
+		// A slug cut off at the limit doesn't end with a dash
+		{strings.Repeat("a", maxSlugLength-1) + " b", strings.Repeat("a", maxSlugLength-1)},
+	}
+	for _, test := range tests {
+		if got := Slugify(test.title); got != test.want {
+			t.Errorf("Slugify(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		headline string
+		want     string
+	}{
+		{"A day in \x01Rome\x02.", "A day in <mark>Rome</mark>."},
+		{"\x01Rome\x02 & \x01Paris\x02", "<mark>Rome</mark> &amp; <mark>Paris</mark>"},
+		{"<b>\x01Rome\x02</b>", "&lt;b&gt;<mark>Rome</mark>&lt;/b&gt;"},
+		// Markers people type themselves are just text
+		{"[[hl]]Rome[[/hl]]", "[[hl]]Rome[[/hl]]"},
+		{"</mark>\x01Rome\x02<mark>", "&lt;/mark&gt;<mark>Rome</mark>&lt;mark&gt;"},
+		// Stray markers never leave a tag open or closed on its own
+		{"\x02Rome\x01", "Rome<mark></mark>"},
+		{"\x01\x01Rome\x02\x02", "<mark>Rome</mark>"},
+		{"\x01Rome", "<mark>Rome</mark>"},
+	}
+	for _, test := range tests {
+		r := GallerySearchResult{Headline: test.headline}
+		if got := string(r.Highlight()); got != test.want {
+			t.Errorf("Highlight() of %q = %q, want %q", test.headline, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Limits on how large the photos uploaded with a guest link can be
+const (
+	DefaultGuestSizeMB = 10
+	MaxGuestSizeMB     = 50
+)
+
+// GuestLink lets anyone who opens it upload photos to a gallery without an account, like the guests at a wedding.
+// Like ShareLink, only a hash of the token is stored. Photos uploaded with the link wait in a GuestUpload until the
+// gallery's owner approves them.
+//
+// MaxUploads limits how many photos can be uploaded with the link; zero means there is no limit. MaxSizeMB limits
+// how large each of them can be.
+type GuestLink struct {
+	gorm.Model
+	GalleryID  uint `gorm:"not null;index"`
+	Label      string
+	Token      string `gorm:"-"`
+	TokenHash  string `gorm:"not null;unique_index"`
+	MaxUploads int    `gorm:"not null;default:0"`
+	MaxSizeMB  int    `gorm:"not null;default:10"`
+	Uploads    int    `gorm:"not null;default:0"`
+	RevokedAt  *time.Time
+}
+
+// Revoked reports whether the owner has revoked the link.
+func (gl *GuestLink) Revoked() bool {
+	return gl.RevokedAt != nil
+}
+
+// UploadsExhausted reports whether as many photos have been uploaded with the link as it allows.
+func (gl *GuestLink) UploadsExhausted() bool {
+	return gl.MaxUploads > 0 && gl.Uploads >= gl.MaxUploads
+}
+
+// Remaining returns how many more photos can be uploaded with the link, or -1 if there is no limit.
+func (gl *GuestLink) Remaining() int {
+	if gl.MaxUploads == 0 {
+		return -1
+	}
+	if gl.UploadsExhausted() {
+		return 0
+	}
+	return gl.MaxUploads - gl.Uploads
+}
+
+// MaxBytes is the size limit of each photo in bytes.
+func (gl *GuestLink) MaxBytes() int64 {
+	return int64(gl.MaxSizeMB) << 20
+}
+
+// Status describes the state of the link for its owner.
+func (gl *GuestLink) Status() string {
+	switch {
+	case gl.Revoked():
+		return "revoked"
+	case gl.UploadsExhausted():
+		return "used up"
+	}
+	return "active"
+}
+
+// GuestUpload is a photo uploaded with a GuestLink that is waiting for the gallery's owner to approve it. Until then
+// the file is kept outside of the gallery, where only the owner can see it. Approving or rejecting the photo removes
+// it from the queue.
+type GuestUpload struct {
+	gorm.Model
+	GalleryID   uint   `gorm:"not null;index"`
+	GuestLinkID uint   `gorm:"not null;index"`
+	GuestName   string `gorm:"not null"`
+	Filename    string `gorm:"not null"`
+}
+
+// Path is the URL the owner previews the photo at.
+func (gu *GuestUpload) Path() string {
+	return fmt.Sprintf("/galleries/%d/guests/uploads/%d", gu.GalleryID, gu.ID)
+}
+
+// RelativePath is where the photo is stored on disk while it waits to be approved. The upload's ID keeps photos with
+// the same filename apart.
+func (gu *GuestUpload) RelativePath() string {
+	return filepath.ToSlash(filepath.Join(GuestUploadDir(gu.GalleryID), fmt.Sprintf("%d-%s", gu.ID, gu.Filename)))
+}
+
+// GuestUploadDir is the directory a gallery's photos waiting to be approved are stored in.
+func GuestUploadDir(galleryID uint) string {
+	return filepath.Join("images", "guests", fmt.Sprintf("%v", galleryID))
+}
@@ -4,27 +4,116 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
 )
 
 // Image is used to represent images stored in a Gallery.
-// Image is NOT stored in the database, and instead references data stored on disk.
+// Image is NOT stored in the database, and instead references data stored on disk. Anything we know about an image
+// beyond its file, like its caption, is kept in an ImageDetail.
 type Image struct {
 	GalleryID uint
 	Filename  string
+	Caption   string
+	// GuestName is who uploaded the image with a guest link, or empty if it was uploaded by the gallery's owner or a
+	// collaborator
+	GuestName string
+	// Version counts how many times the image has been replaced, starting from 1 for the image as it was first
+	// uploaded
+	Version int
+	// Width and Height are the image's size in pixels, or 0 if it couldn't be read
+	Width  int
+	Height int
+}
+
+// ImageDetail stores the parts of an Image that don't live on disk. Images are matched to their details by gallery
+// and filename.
+type ImageDetail struct {
+	gorm.Model
+	GalleryID uint   `gorm:"not null;index"`
+	Filename  string `gorm:"not null"`
+	Caption   string
+	GuestName string
+	Version   int `gorm:"not null;default:1"`
+	// Width and Height are read from the file whenever it changes, so that galleries can be laid out without
+	// opening every image
+	Width  int `gorm:"not null;default:0"`
+	Height int `gorm:"not null;default:0"`
+}
+
+// ImageVersion is an earlier version of an image, kept when the image was replaced so that it can be restored. The
+// image's current version is never an ImageVersion; it is the file in the gallery.
+type ImageVersion struct {
+	gorm.Model
+	GalleryID uint   `gorm:"not null;index"`
+	Filename  string `gorm:"not null"`
+	Version   int    `gorm:"not null"`
+}
+
+// AspectRatio returns the image's width divided by its height. Images whose size we don't know are treated as square.
+func (i *Image) AspectRatio() float64 {
+	if i.Width <= 0 || i.Height <= 0 {
+		return 1
+	}
+	return float64(i.Width) / float64(i.Height)
 }
 
 // Path is used to build the absolute path used to reference this image via a web request.
 func (i *Image) Path() string {
 	// Create a properly encoded URL path - handles special characters in filenames
 	temp := url.URL{
-		Path: "/" + i.RelativePath(),
+		Path:     "/" + i.RelativePath(),
+		RawQuery: i.VersionQuery(),
+	}
+	return temp.String()
+}
+
+// DownloadPath is like Path, but asks for the image to be downloaded rather than shown in the browser.
+func (i *Image) DownloadPath() string {
+	query := "download=1"
+	if v := i.VersionQuery(); v != "" {
+		query += "&" + v
+	}
+	temp := url.URL{
+		Path:     "/" + i.RelativePath(),
+		RawQuery: query,
 	}
 	return temp.String()
 }
 
+// VersionQuery is the query string that puts the image's version in its URLs once it has been replaced. The file is
+// always served from the same path, so the version is what gives each version its own URL, and stops browsers and
+// other caches from showing a version that has been replaced.
+func (i *Image) VersionQuery() string {
+	if i.Version <= 1 {
+		return ""
+	}
+	return "v=" + strconv.Itoa(i.Version)
+}
+
 // RelativePath is used to build the path to this image on our local disk, relative to where our Go application is run from.
 func (i *Image) RelativePath() string {
 	// Convert the gallery ID to a string
 	galleryID := fmt.Sprintf("%v", i.GalleryID)
 	return filepath.ToSlash(filepath.Join("images", "galleries", galleryID, i.Filename))
 }
+
+// Path is where the owner can see the version.
+func (v *ImageVersion) Path() string {
+	temp := url.URL{
+		Path: fmt.Sprintf("/galleries/%d/images/%s/versions/%d", v.GalleryID, v.Filename, v.Version),
+	}
+	return temp.String()
+}
+
+// RelativePath is where the version is stored on disk. Versions are kept outside the gallery's own directory, which
+// must only hold its current images.
+func (v *ImageVersion) RelativePath() string {
+	return filepath.ToSlash(filepath.Join(ImageVersionDir(v.GalleryID), fmt.Sprintf("%d-%s", v.Version, v.Filename)))
+}
+
+// ImageVersionDir is the directory the earlier versions of a gallery's images are stored in.
+func ImageVersionDir(galleryID uint) string {
+	return filepath.Join("images", "versions", fmt.Sprintf("%v", galleryID))
+}
@@ -0,0 +1,103 @@
+package models
+
+import "strconv"
+
+// Gallery layouts. Grid shows images as uniform tiles, masonry stacks them in Columns columns, justified fits them into
+// rows of equal height that fill the page, and story shows one image after another at full width.
+const (
+	LayoutGrid      = "grid"
+	LayoutMasonry   = "masonry"
+	LayoutJustified = "justified"
+	LayoutStory     = "story"
+)
+
+// The number of columns a masonry layout can have
+const (
+	MinColumns     = 2
+	MaxColumns     = 6
+	DefaultColumns = 4
+)
+
+// justifiedRowRatio is the aspect ratio a justified row aims for, i.e. a row is this many times wider than it is tall.
+// It fits about four landscape images or six portrait ones into a row.
+const justifiedRowRatio = 5.0
+
+// LayoutColumns returns the number of columns of a masonry layout, falling back to the default for galleries that
+// haven't picked one.
+func (g *Gallery) LayoutColumns() int {
+	if g.Columns < MinColumns || g.Columns > MaxColumns {
+		return DefaultColumns
+	}
+	return g.Columns
+}
+
+// ColumnChoices lists the number of columns a masonry layout can have.
+func (g *Gallery) ColumnChoices() []int {
+	choices := make([]int, 0, MaxColumns-MinColumns+1)
+	for n := MinColumns; n <= MaxColumns; n++ {
+		choices = append(choices, n)
+	}
+	return choices
+}
+
+// MasonryColumns splits the gallery's images into columns for the masonry layout. Each image goes into whichever column
+// is shortest so far, so the columns end up about the same height while the images still read roughly left to right.
+func (g *Gallery) MasonryColumns() [][]Image {
+	n := g.LayoutColumns()
+	columns := make([][]Image, n)
+	heights := make([]float64, n)
+	for _, img := range g.Images {
+		shortest := 0
+		for i := range heights {
+			if heights[i] < heights[shortest] {
+				shortest = i
+			}
+		}
+		columns[shortest] = append(columns[shortest], img)
+		// Every column is the same width, so an image's height in it is proportional to 1 / aspect ratio
+		heights[shortest] += 1 / img.AspectRatio()
+	}
+	return columns
+}
+
+// JustifiedImage is an image in a justified row, along with how much of the row's width it takes up.
+type JustifiedImage struct {
+	Image
+	// WidthPercent is the percentage of the row's width the image takes up
+	WidthPercent float64
+}
+
+// CSSWidth returns WidthPercent in a form that can be used in CSS.
+func (j JustifiedImage) CSSWidth() string {
+	return strconv.FormatFloat(j.WidthPercent, 'f', 4, 64) + "%"
+}
+
+// JustifiedRows splits the gallery's images into rows for the justified layout. Images are added to a row until it is
+// wide enough, and then each is given a share of the row's width in proportion to its aspect ratio. That makes every
+// image in a row the same height whatever the width of the page. The last row is left short rather than stretching
+// its images to fill the page.
+func (g *Gallery) JustifiedRows() [][]JustifiedImage {
+	var rows [][]JustifiedImage
+	var row []Image
+	total := 0.0
+	addRow := func(width float64) {
+		justified := make([]JustifiedImage, len(row))
+		for i, img := range row {
+			justified[i] = JustifiedImage{img, img.AspectRatio() / width * 100}
+		}
+		rows = append(rows, justified)
+		row = nil
+		total = 0
+	}
+	for _, img := range g.Images {
+		row = append(row, img)
+		total += img.AspectRatio()
+		if total >= justifiedRowRatio {
+			addRow(total)
+		}
+	}
+	if len(row) > 0 {
+		addRow(justifiedRowRatio)
+	}
+	return rows
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// sized returns images with the given width:height sizes, as pairs of numbers.
+func sized(sizes ...int) []Image {
+	images := make([]Image, len(sizes)/2)
+	for i := range images {
+		images[i] = Image{Filename: string(rune('a' + i)), Width: sizes[2*i], Height: sizes[2*i+1]}
+	}
+	return images
+}
+
+func TestJustifiedRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []Image
+		// want holds each row's widths as percentages
+		want [][]float64
+	}{
+		{"no images", nil, nil},
+		// Five squares make one full row of 5:1
+		{"full row", sized(1, 1, 1, 1, 1, 1, 1, 1, 1, 1), [][]float64{{20, 20, 20, 20, 20}}},
+		// Two 3:1 panoramas overshoot the row ratio, so they are scaled down to fit
+		{"overfull row", sized(3, 1, 3, 1), [][]float64{{50, 50}}},
+		{"mixed row", sized(4, 1, 2, 2, 1, 2), [][]float64{{80, 20}, {10}}},
+		// The last row keeps the height of a full row instead of stretching to fill the page
+		{"short last row", sized(1, 1, 2, 1), [][]float64{{20, 40}}},
+		// Images whose size we don't know are laid out as squares
+		{"unknown size", sized(0, 0, 4, 0), [][]float64{{20, 20}}},
+		{"landscape and portrait", sized(3, 2, 3, 2, 3, 2, 1, 2, 2, 3, 2, 3, 2, 3),
+			[][]float64{{30, 30, 30, 10}, {40.0 / 3, 40.0 / 3, 40.0 / 3}}},
+	}
+	for _, test := range tests {
+		rows := (&Gallery{Images: test.images}).JustifiedRows()
+		if len(rows) != len(test.want) {
+			t.Errorf("%s: got %d rows, want %d", test.name, len(rows), len(test.want))
+			continue
+		}
+		n := 0
+		for i, row := range rows {
+			if len(row) != len(test.want[i]) {
+				t.Errorf("%s: row %d has %d images, want %d", test.name, i, len(row), len(test.want[i]))
+				continue
+			}
+			for j, img := range row {
+				if img.Filename != test.images[n].Filename {
+					t.Errorf("%s: row %d image %d is %q, want %q", test.name, i, j, img.Filename, test.images[n].Filename)
+				}
+				n++
+				if math.Abs(img.WidthPercent-test.want[i][j]) > 1e-9 {
+					t.Errorf("%s: row %d image %d is %v%% wide, want %v%%", test.name, i, j, img.WidthPercent, test.want[i][j])
+				}
+			}
+		}
+	}
+}
+
+func TestJustifiedRowsKeepImagesTheSameHeight(t *testing.T) {
+	g := &Gallery{Images: sized(3, 2, 2, 3, 16, 9, 1, 1, 9, 16, 4, 3, 3, 1, 1, 2)}
+	for i, row := range g.JustifiedRows() {
+		// An image's height is its width divided by its aspect ratio
+		height := row[0].WidthPercent / row[0].AspectRatio()
+		for j, img := range row[1:] {
+			if h := img.WidthPercent / img.AspectRatio(); math.Abs(h-height) > 1e-9 {
+				t.Errorf("row %d image %d is %v high, want %v", i, j+1, h, height)
+			}
+		}
+	}
+}
+
+func TestCSSWidth(t *testing.T) {
+	if got, want := (JustifiedImage{WidthPercent: 100.0 / 3}).CSSWidth(), "33.3333%"; got != want {
+		t.Errorf("CSSWidth() = %q, want %q", got, want)
+	}
+}
+
+func TestMasonryColumns(t *testing.T) {
+	// Tall images fill up a column faster, so the next images go to the others
+	g := &Gallery{Columns: 2, Images: sized(1, 3, 1, 1, 1, 1, 1, 1)}
+	var got [][]string
+	for _, column := range g.MasonryColumns() {
+		var names []string
+		for _, img := range column {
+			names = append(names, img.Filename)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{{"a"}, {"b", "c", "d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MasonryColumns() = %q, want %q", got, want)
+	}
+}
+
+func TestLayoutColumns(t *testing.T) {
+	for columns, want := range map[int]int{0: DefaultColumns, 1: DefaultColumns, 2: 2, 6: 6, 7: DefaultColumns} {
+		if got := (&Gallery{Columns: columns}).LayoutColumns(); got != want {
+			t.Errorf("LayoutColumns() with %d columns = %d, want %d", columns, got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "github.com/jinzhu/gorm"
+
+// Like is a user liking a public gallery, or a single image in it when Filename is set. A user can like each gallery
+// and image once; unliking deletes the like for good.
+type Like struct {
+	gorm.Model
+	UserID    uint   `gorm:"not null;index"`
+	GalleryID uint   `gorm:"not null;index"`
+	Filename  string `gorm:"not null;default:''"`
+}
+
+// OnImage reports whether the like is for a single image rather than the whole gallery.
+func (l *Like) OnImage() bool {
+	return l.Filename != ""
+}
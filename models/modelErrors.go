@@ -32,8 +32,61 @@ var (
 	ErrRememberRequired  ModelError = "models: remember token is required"
 	ErrRememberTooShort  ModelError = "models: remember token must be at least 32 bytes"
 	ErrUserIDRequired    ModelError = "models: user ID is required"
+	ErrUsernameInvalid   ModelError = "models: username must be 3 to 30 lowercase letters, numbers, dashes or underscores"
+	ErrUsernameReserved  ModelError = "models: that username is reserved"
+	ErrUsernameTaken     ModelError = "models: username is already taken"
+	ErrBioTooLong        ModelError = "models: bio must be 500 characters or less"
+	ErrWebsiteInvalid    ModelError = "models: website must be a http or https address"
+	ErrAvatarInvalid     ModelError = "models: avatar must be a JPEG, PNG or GIF image"
 	// gallery
-	ErrTitleRequired ModelError = "models: title is required"
+	ErrTitleRequired     ModelError = "models: title is required"
+	ErrDescriptionLong   ModelError = "models: description must be 10,000 characters or less"
+	ErrVisibilityInvalid ModelError = "models: visibility must be private, unlisted or public"
+	ErrSortInvalid       ModelError = "models: galleries can only be sorted by created, updated, title or images"
+	ErrCursorInvalid     ModelError = "models: page cursor provided is not valid"
+	ErrScheduleInvalid   ModelError = "models: publish and expiry times must be a valid date and time"
+	ErrExpiryBeforePub   ModelError = "models: a gallery must expire after it is published"
+	ErrSlugTaken         ModelError = "models: you already have a gallery at that address"
+	ErrLayoutInvalid     ModelError = "models: layout must be grid, masonry, justified or story"
+	ErrColumnsInvalid    ModelError = "models: masonry layouts must have between 2 and 6 columns"
+	// image versions
+	ErrReplacementInvalid ModelError = "models: the new version must be an image of the same type as the one it replaces"
 	// pwReset
 	ErrTokenInvalid ModelError = "models: token provided is not valid"
+	// selection
+	ErrNameRequired       ModelError = "models: name is required"
+	ErrGalleryIDRequired  ModelError = "models: gallery ID is required"
+	ErrFilenameRequired   ModelError = "models: filename is required"
+	ErrSelectionSubmitted ModelError = "models: this selection has already been submitted"
+	ErrTooManySelections  ModelError = "models: you are starting selections too quickly, please wait a while"
+	// shareLink
+	ErrShareLinkInvalid ModelError = "models: this share link has expired or is no longer valid"
+	ErrMaxViewsInvalid  ModelError = "models: view limit can't be negative"
+	// collaborator
+	ErrRoleInvalid         ModelError = "models: role must be viewer, contributor or editor"
+	ErrCollaboratorInvited ModelError = "models: that email address has already been invited to this gallery"
+	// transfer
+	ErrTransferPending    ModelError = "models: this gallery is already being transferred, cancel that transfer first"
+	ErrTransferToSelf     ModelError = "models: you already own this gallery"
+	ErrTransferNotPending ModelError = "models: this transfer has already been accepted or cancelled"
+	ErrTransferWrongUser  ModelError = "models: this transfer was sent to a different email address, please sign in with that one"
+	// comment
+	ErrCommentModeInvalid ModelError = "models: comments must be off, moderated or open"
+	ErrCommentRequired    ModelError = "models: comment can't be empty"
+	ErrCommentTooLong     ModelError = "models: comment must be 2,000 characters or less"
+	ErrCommentsClosed     ModelError = "models: comments are turned off for this gallery"
+	ErrTooManyComments    ModelError = "models: you are commenting too quickly, please wait a few minutes"
+	// collection
+	ErrCollectionInvalid ModelError = "models: collection provided is not valid"
+	ErrCollectionCycle   ModelError = "models: a collection can't be moved inside itself"
+	// guest uploads
+	ErrGuestLinkInvalid  ModelError = "models: this upload link has been revoked or is no longer valid"
+	ErrGuestUploadsUsed  ModelError = "models: this upload link can't take any more photos"
+	ErrGuestImageTooBig  ModelError = "models: that photo is larger than this upload link allows"
+	ErrGuestImageInvalid ModelError = "models: photos must be JPEG, PNG or GIF images"
+	ErrGuestNameTooLong  ModelError = "models: name must be 100 characters or less"
+	ErrMaxUploadsInvalid ModelError = "models: photo limit can't be negative"
+	ErrMaxSizeInvalid    ModelError = "models: size limit must be between 1 and 50 MB"
+	// follow
+	ErrFollowSelf ModelError = "models: you can't follow yourself"
 )
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Selection is a client's proofing pick list for a single gallery. The client is identified by their name and email
+// address, and the browser they proof from holds the (unhashed) Token in a cookie. A photographer can also start a
+// selection for a client and send them a link with the Token in it, which identifies them without asking.
+type Selection struct {
+	gorm.Model
+	GalleryID   uint   `gorm:"not null;index"`
+	Name        string `gorm:"not null"`
+	Email       string `gorm:"not null"`
+	Note        string
+	Token       string `gorm:"-"`
+	TokenHash   string `gorm:"not null;unique_index"`
+	SubmittedAt *time.Time
+	Images      []SelectionImage `gorm:"-"`
+}
+
+// SelectionImage is a single image a client has hearted. Images are stored on disk, so we reference them by filename.
+type SelectionImage struct {
+	gorm.Model
+	SelectionID uint   `gorm:"not null;index"`
+	Filename    string `gorm:"not null"`
+}
+
+// Submitted reports whether the client has sent their final selection to the photographer.
+func (s *Selection) Submitted() bool {
+	return s.SubmittedAt != nil
+}
+
+// Hearted reports whether the image with the given filename is part of the selection.
+func (s *Selection) Hearted(filename string) bool {
+	for _, img := range s.Images {
+		if img.Filename == filename {
+			return true
+		}
+	}
+	return false
+}
+
+// Filenames returns the filenames of every hearted image, in the order they were hearted.
+func (s *Selection) Filenames() []string {
+	ret := make([]string, len(s.Images))
+	for i, img := range s.Images {
+		ret[i] = img.Filename
+	}
+	return ret
+}
+
+// SelectionHeart is used to render the heart button beneath a single image.
+type SelectionHeart struct {
+	GalleryID uint
+	Filename  string
+	Hearted   bool
+	Submitted bool
+}
+
+// HeartData returns what a template needs to render the heart button for the given image.
+func (s *Selection) HeartData(img Image) SelectionHeart {
+	return SelectionHeart{
+		GalleryID: img.GalleryID,
+		Filename:  img.Filename,
+		Hearted:   s.Hearted(img.Filename),
+		Submitted: s.Submitted(),
+	}
+}
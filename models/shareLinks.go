@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ShareLink grants view access to a gallery to anyone who opens it, even if the gallery is private. Like PwReset, only
+// a hash of the token is stored so the full link is only ever shown to the owner when it is created.
+//
+// MaxViews limits how many times the link can be opened; zero means there is no limit.
+type ShareLink struct {
+	gorm.Model
+	GalleryID uint `gorm:"not null;index"`
+	Label     string
+	Token     string `gorm:"-"`
+	TokenHash string `gorm:"not null;unique_index"`
+	ExpiresAt *time.Time
+	MaxViews  int `gorm:"not null;default:0"`
+	Views     int `gorm:"not null;default:0"`
+	RevokedAt *time.Time
+}
+
+// Revoked reports whether the owner has revoked the link.
+func (sl *ShareLink) Revoked() bool {
+	return sl.RevokedAt != nil
+}
+
+// Expired reports whether the link's expiry date has passed.
+func (sl *ShareLink) Expired() bool {
+	return sl.ExpiresAt != nil && time.Now().After(*sl.ExpiresAt)
+}
+
+// ViewsExhausted reports whether the link has been opened as many times as it allows.
+func (sl *ShareLink) ViewsExhausted() bool {
+	return sl.MaxViews > 0 && sl.Views >= sl.MaxViews
+}
+
+// Valid reports whether the link still grants access to visitors who already opened it.
+func (sl *ShareLink) Valid() bool {
+	return !sl.Revoked() && !sl.Expired()
+}
+
+// Status describes the state of the link for its owner.
+func (sl *ShareLink) Status() string {
+	switch {
+	case sl.Revoked():
+		return "revoked"
+	case sl.Expired():
+		return "expired"
+	case sl.ViewsExhausted():
+		return "used up"
+	}
+	return "active"
+}
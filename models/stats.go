@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// GalleryStat counts how many times a gallery, or one of its images, was viewed and downloaded on one day. Counts are
+// aggregated as they happen and nothing is stored about the visitors themselves, not even their IP addresses. Rows
+// with an empty Filename count views of the gallery page; the others count views and downloads of a single image.
+type GalleryStat struct {
+	ID        uint      `gorm:"primary_key"`
+	GalleryID uint      `gorm:"not null"`
+	Day       time.Time `gorm:"type:date;not null"`
+	Filename  string    `gorm:"not null;default:''"`
+	Views     int       `gorm:"not null;default:0"`
+	Downloads int       `gorm:"not null;default:0"`
+}
+
+// GalleryStats summarises a gallery's stats over a number of days, for showing to its owner.
+type GalleryStats struct {
+	Days      []DayStats
+	Images    []ImageStats
+	Views     int
+	Downloads int
+}
+
+// DayStats are the totals for one day. Height is the day's views as a percentage of the busiest day, for drawing a
+// bar chart.
+type DayStats struct {
+	Day        time.Time
+	Views      int
+	ImageViews int
+	Downloads  int
+	Height     int
+}
+
+// ImageStats are the totals for one image.
+type ImageStats struct {
+	Filename  string
+	Views     int
+	Downloads int
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Transfer is an offer to hand a gallery over to another account. The offer is emailed to ToEmail, and whoever signs
+// in with that address can accept it with the token from the email before ExpiresAt. Like PwReset, only a hash of the
+// token is stored.
+type Transfer struct {
+	gorm.Model
+	GalleryID   uint   `gorm:"not null;index"`
+	FromUserID  uint   `gorm:"not null"`
+	ToEmail     string `gorm:"not null"`
+	ToUserID    uint
+	Token       string `gorm:"-"`
+	TokenHash   string `gorm:"not null;unique_index"`
+	ExpiresAt   time.Time
+	AcceptedAt  *time.Time
+	CancelledAt *time.Time
+}
+
+// Pending reports whether the transfer can still be accepted.
+func (t *Transfer) Pending() bool {
+	return t.AcceptedAt == nil && t.CancelledAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// Things that can happen to a gallery that are recorded in its audit trail
+const (
+	AuditTransferRequested = "transfer_requested"
+	AuditTransferCancelled = "transfer_cancelled"
+	AuditTransferAccepted  = "transfer_accepted"
+)
+
+// AuditEntry records something that happened to a gallery which its owners, past and present, may need to look back
+// on. UserID is whoever did it. Entries outlive the gallery, so they aren't deleted along with it.
+type AuditEntry struct {
+	gorm.Model
+	GalleryID uint   `gorm:"not null;index"`
+	UserID    uint   `gorm:"not null"`
+	Action    string `gorm:"not null"`
+	Detail    string
+}
+
+// Description describes the entry for people.
+func (a *AuditEntry) Description() string {
+	switch a.Action {
+	case AuditTransferRequested:
+		return "Transfer offered"
+	case AuditTransferCancelled:
+		return "Transfer cancelled"
+	case AuditTransferAccepted:
+		return "Ownership transferred"
+	}
+	return a.Action
+}
@@ -1,16 +1,51 @@
 package models
 
 import (
+	"net/url"
+	"path"
+	"strconv"
+
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 )
 
+// User is a photographer with an account. Username, Bio, Avatar and Website are shown on their public profile page,
+// which only exists once they have picked a username. Usernames are unique among users that have one; the index for
+// that is created in services.AutoMigrate since it is a partial index.
 type User struct {
 	gorm.Model
 	Name         string
 	Email        string `gorm:"not null;unique_index"`
+	Username     string
+	Bio          string `gorm:"type:text"`
+	Avatar       string
+	Website      string
 	Password     string `gorm:"-"`
 	PasswordHash string `gorm:"not null"`
 	Remember     string `gorm:"-"`
 	RememberHash string `gorm:"not null;unique_index"`
 }
+
+// ProfilePath is the path of the user's public profile page, or an empty string if they don't have one yet.
+func (u *User) ProfilePath() string {
+	if u.Username == "" {
+		return ""
+	}
+	return "/u/" + url.PathEscape(u.Username)
+}
+
+// AvatarPath is the path the user's avatar is served from, or an empty string if they haven't uploaded one.
+func (u *User) AvatarPath() string {
+	if u.Avatar == "" {
+		return ""
+	}
+	temp := url.URL{
+		Path: "/" + u.AvatarRelativePath(),
+	}
+	return temp.String()
+}
+
+// AvatarRelativePath is the path to the user's avatar on our local disk.
+func (u *User) AvatarRelativePath() string {
+	return path.Join("images", "avatars", strconv.Itoa(int(u.ID)), u.Avatar)
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts events per key (eg an IP address) over a sliding window of time. It is safe for concurrent use.
+//
+// Counts are only kept in memory, so they are reset whenever the application restarts. That is fine for slowing down
+// password guessing and spam, which is all we use it for.
+type Limiter struct {
+	max    int
+	window time.Duration
+
+	mu        sync.Mutex
+	hits      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// New returns a Limiter that allows at most max events per key within the given window.
+func New(max int, window time.Duration) *Limiter {
+	return &Limiter{
+		max:    max,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Exceeded reports whether the key has used up all of its events for the current window.
+func (l *Limiter) Exceeded(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.recent(key, time.Now())) >= l.max
+}
+
+// Hit records an event for the key.
+func (l *Limiter) Hit(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.hits[key] = append(l.recent(key, now), now)
+	l.sweep(now)
+}
+
+// Allow records an event for the key and reports whether it was within the limit. Events over the limit aren't
+// recorded, so a key that keeps trying is let in again once its earlier events fall out of the window.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	hits := l.recent(key, now)
+	if len(hits) >= l.max {
+		return false
+	}
+	l.hits[key] = append(hits, now)
+	l.sweep(now)
+	return true
+}
+
+// Reset forgets every event recorded for the key.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.hits, key)
+}
+
+// recent drops the events for key that fall outside the window and returns the rest. The caller must hold l.mu.
+func (l *Limiter) recent(key string, now time.Time) []time.Time {
+	hits := l.hits[key]
+	cutoff := now.Add(-l.window)
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) == 0 {
+		delete(l.hits, key)
+		return nil
+	}
+	l.hits[key] = hits
+	return hits
+}
+
+// sweep removes keys with no recent events so that the map doesn't grow forever. It runs at most once per window.
+// The caller must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for key := range l.hits {
+		l.recent(key, now)
+	}
+}
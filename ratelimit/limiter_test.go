@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	l := New(3, time.Hour)
+	for i := 0; i < 3; i++ {
+		if !l.Allow("a") {
+			t.Fatalf("Allow #%d = false, want true", i+1)
+		}
+	}
+	if l.Allow("a") {
+		t.Error("Allow over the limit = true, want false")
+	}
+	if !l.Allow("b") {
+		t.Error("Allow for another key = false, want true")
+	}
+}
+
+func TestAllowAfterWindow(t *testing.T) {
+	l := New(2, 20*time.Millisecond)
+	l.Allow("a")
+	l.Allow("a")
+	if l.Allow("a") {
+		t.Fatal("Allow over the limit = true, want false")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !l.Allow("a") {
+		t.Error("Allow once the window has passed = false, want true")
+	}
+}
+
+func TestAllowDoesNotRecordRefusals(t *testing.T) {
+	l := New(1, time.Hour)
+	l.Allow("a")
+	for i := 0; i < 5; i++ {
+		l.Allow("a")
+	}
+	if n := len(l.hits["a"]); n != 1 {
+		t.Errorf("recorded %d hits, want 1", n)
+	}
+}
+
+func TestExceededAndHit(t *testing.T) {
+	l := New(2, time.Hour)
+	if l.Exceeded("a") {
+		t.Fatal("Exceeded with no hits = true, want false")
+	}
+	l.Hit("a")
+	if l.Exceeded("a") {
+		t.Fatal("Exceeded after 1 of 2 hits = true, want false")
+	}
+	l.Hit("a")
+	if !l.Exceeded("a") {
+		t.Fatal("Exceeded after 2 of 2 hits = false, want true")
+	}
+	l.Reset("a")
+	if l.Exceeded("a") {
+		t.Error("Exceeded after Reset = true, want false")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l := New(5, 20*time.Millisecond)
+	l.Hit("a")
+	l.Hit("b")
+	time.Sleep(30 * time.Millisecond)
+	l.Hit("c")
+	if _, ok := l.hits["a"]; ok {
+		t.Error("sweep kept a key with no recent hits")
+	}
+	if _, ok := l.hits["c"]; !ok {
+		t.Error("sweep dropped a key with recent hits")
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements CollaboratorDBInt interface
+
+type collaboratorGorm struct {
+	db *gorm.DB
+}
+
+func (cg *collaboratorGorm) ByID(id uint) (*models.Collaborator, error) {
+	var collaborator models.Collaborator
+	err := first(cg.db.Where("id = ?", id), &collaborator)
+	if err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+func (cg *collaboratorGorm) ByToken(tokenHash string) (*models.Collaborator, error) {
+	var collaborator models.Collaborator
+	err := first(cg.db.Where("token_hash = ?", tokenHash), &collaborator)
+	if err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+func (cg *collaboratorGorm) ByGalleryID(galleryID uint) ([]models.Collaborator, error) {
+	var collaborators []models.Collaborator
+	db := cg.db.Where("gallery_id = ?", galleryID).Order("created_at")
+	if err := db.Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
+
+func (cg *collaboratorGorm) ByUserID(userID uint) ([]models.Collaborator, error) {
+	var collaborators []models.Collaborator
+	db := cg.db.Where("user_id = ? AND accepted_at IS NOT NULL", userID).Order("accepted_at desc")
+	if err := db.Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
+
+func (cg *collaboratorGorm) ByGalleryAndEmail(galleryID uint, email string) (*models.Collaborator, error) {
+	var collaborator models.Collaborator
+	err := first(cg.db.Where("gallery_id = ? AND email = ?", galleryID, email), &collaborator)
+	if err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+func (cg *collaboratorGorm) ByGalleryAndUser(galleryID, userID uint) (*models.Collaborator, error) {
+	var collaborator models.Collaborator
+	db := cg.db.Where("gallery_id = ? AND user_id = ? AND accepted_at IS NOT NULL", galleryID, userID)
+	err := first(db, &collaborator)
+	if err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+func (cg *collaboratorGorm) Create(collaborator *models.Collaborator) error {
+	return cg.db.Create(collaborator).Error
+}
+
+func (cg *collaboratorGorm) Update(collaborator *models.Collaborator) error {
+	return cg.db.Save(collaborator).Error
+}
+
+// Claim binds the invitation to the user only if nobody has accepted it yet, so that two accounts redeeming the same
+// token at once can't both get in.
+func (cg *collaboratorGorm) Claim(collaborator *models.Collaborator, userID uint) error {
+	now := time.Now()
+	db := cg.db.Model(collaborator).Where("accepted_at IS NULL").
+		UpdateColumns(map[string]interface{}{"user_id": userID, "accepted_at": now})
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return models.ErrTokenInvalid
+	}
+	collaborator.UserID = userID
+	collaborator.AcceptedAt = &now
+	return nil
+}
+
+func (cg *collaboratorGorm) Delete(id uint) error {
+	collaborator := models.Collaborator{Model: gorm.Model{ID: id}}
+	return cg.db.Delete(&collaborator).Error
+}
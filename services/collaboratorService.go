@@ -0,0 +1,51 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type collaboratorService struct {
+	interfaces.CollaboratorDBInt
+}
+
+func NewCollaboratorService(db *gorm.DB, hmacKey string) interfaces.CollaboratorServiceInt {
+	return &collaboratorService{
+		CollaboratorDBInt: NewCollaboratorValidator(&collaboratorGorm{db}, hash.NewHMAC(hmacKey)),
+	}
+}
+
+func (cs *collaboratorService) Accept(token string, user *models.User) (*models.Collaborator, error) {
+	collaborator, err := cs.ByToken(token)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, models.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if collaborator.Accepted() {
+		if collaborator.UserID == user.ID {
+			return collaborator, nil
+		}
+		return nil, models.ErrTokenInvalid
+	}
+	// The invitation is for whoever it was sent to, not whoever ends up with the link
+	if collaborator.Email != user.Email {
+		return nil, models.ErrTokenInvalid
+	}
+	if err := cs.Claim(collaborator, user.ID); err != nil {
+		return nil, err
+	}
+	return collaborator, nil
+}
+
+func (cs *collaboratorService) Role(galleryID, userID uint) string {
+	collaborator, err := cs.ByGalleryAndUser(galleryID, userID)
+	if err != nil {
+		return ""
+	}
+	return collaborator.Role
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+// inviteDB is a CollaboratorDBInt holding a single invitation.
+type inviteDB struct {
+	interfaces.CollaboratorDBInt
+	invite *models.Collaborator
+	// claimed makes Claim fail as though another user got there first
+	claimed bool
+}
+
+func (db *inviteDB) ByToken(token string) (*models.Collaborator, error) {
+	if token != "token" {
+		return nil, models.ErrNotFound
+	}
+	c := *db.invite
+	return &c, nil
+}
+
+func (db *inviteDB) Claim(collaborator *models.Collaborator, userID uint) error {
+	if db.claimed {
+		return models.ErrTokenInvalid
+	}
+	now := time.Now()
+	collaborator.UserID = userID
+	collaborator.AcceptedAt = &now
+	return nil
+}
+
+func TestCollaboratorAccept(t *testing.T) {
+	invitee := &models.User{Email: "ada@example.com"}
+	invitee.ID = 1
+	stranger := &models.User{Email: "mallory@example.com"}
+	stranger.ID = 2
+	accepted := time.Now()
+
+	tests := []struct {
+		name    string
+		invite  models.Collaborator
+		claimed bool
+		token   string
+		user    *models.User
+		want    error
+	}{
+		{"invitee", models.Collaborator{Email: invitee.Email}, false, "token", invitee, nil},
+		{"unknown token", models.Collaborator{Email: invitee.Email}, false, "other", invitee, models.ErrTokenInvalid},
+		{"forwarded link", models.Collaborator{Email: invitee.Email}, false, "token", stranger, models.ErrTokenInvalid},
+		{"lost race", models.Collaborator{Email: invitee.Email}, true, "token", invitee, models.ErrTokenInvalid},
+		{"accepted again", models.Collaborator{Email: invitee.Email, UserID: invitee.ID, AcceptedAt: &accepted},
+			false, "token", invitee, nil},
+		{"accepted by another", models.Collaborator{Email: invitee.Email, UserID: invitee.ID, AcceptedAt: &accepted},
+			false, "token", stranger, models.ErrTokenInvalid},
+	}
+	for _, test := range tests {
+		invite := test.invite
+		cs := &collaboratorService{&inviteDB{invite: &invite, claimed: test.claimed}}
+		collaborator, err := cs.Accept(test.token, test.user)
+		if err != test.want {
+			t.Errorf("%s: Accept returned %v, want %v", test.name, err, test.want)
+			continue
+		}
+		if err == nil && (collaborator.UserID != test.user.ID || !collaborator.Accepted()) {
+			t.Errorf("%s: Accept left the invitation for user %d, accepted %v", test.name, collaborator.UserID,
+				collaborator.Accepted())
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/rand"
+)
+
+type collaboratorValidator struct {
+	interfaces.CollaboratorDBInt
+	hmac       hash.HMAC
+	emailRegex *regexp.Regexp
+}
+
+type collaboratorValFn func(*models.Collaborator) error
+
+func NewCollaboratorValidator(cdb interfaces.CollaboratorDBInt, hmac hash.HMAC) *collaboratorValidator {
+	return &collaboratorValidator{
+		CollaboratorDBInt: cdb,
+		hmac:              hmac,
+		emailRegex: regexp.MustCompile(
+			`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,16}$`),
+	}
+}
+
+func (cv *collaboratorValidator) ByID(id uint) (*models.Collaborator, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return cv.CollaboratorDBInt.ByID(id)
+}
+
+func (cv *collaboratorValidator) ByToken(token string) (*models.Collaborator, error) {
+	collaborator := models.Collaborator{Token: token}
+	if err := runCollaboratorValFns(&collaborator, cv.hmacToken); err != nil {
+		return nil, err
+	}
+	return cv.CollaboratorDBInt.ByToken(collaborator.TokenHash)
+}
+
+func (cv *collaboratorValidator) ByGalleryAndEmail(galleryID uint, email string) (*models.Collaborator, error) {
+	collaborator := models.Collaborator{Email: email}
+	if err := runCollaboratorValFns(&collaborator, cv.normalizeEmail); err != nil {
+		return nil, err
+	}
+	return cv.CollaboratorDBInt.ByGalleryAndEmail(galleryID, collaborator.Email)
+}
+
+func (cv *collaboratorValidator) Create(collaborator *models.Collaborator) error {
+	err := runCollaboratorValFns(collaborator,
+		cv.galleryIDRequired,
+		cv.normalizeEmail,
+		cv.emailRequired,
+		cv.emailFormat,
+		cv.notYetInvited,
+		cv.roleValid,
+		cv.setTokenIfUnset,
+		cv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return cv.CollaboratorDBInt.Create(collaborator)
+}
+
+func (cv *collaboratorValidator) Update(collaborator *models.Collaborator) error {
+	err := runCollaboratorValFns(collaborator,
+		cv.galleryIDRequired,
+		cv.normalizeEmail,
+		cv.emailRequired,
+		cv.emailFormat,
+		cv.roleValid,
+		cv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return cv.CollaboratorDBInt.Update(collaborator)
+}
+
+func (cv *collaboratorValidator) Delete(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return cv.CollaboratorDBInt.Delete(id)
+}
+
+func runCollaboratorValFns(collaborator *models.Collaborator, fns ...collaboratorValFn) error {
+	for _, fn := range fns {
+		if err := fn(collaborator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cv *collaboratorValidator) galleryIDRequired(c *models.Collaborator) error {
+	if c.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (cv *collaboratorValidator) normalizeEmail(c *models.Collaborator) error {
+	c.Email = strings.ToLower(c.Email)
+	c.Email = strings.TrimSpace(c.Email)
+	return nil
+}
+
+func (cv *collaboratorValidator) emailRequired(c *models.Collaborator) error {
+	if c.Email == "" {
+		return models.ErrEmailRequired
+	}
+	return nil
+}
+
+func (cv *collaboratorValidator) emailFormat(c *models.Collaborator) error {
+	if !cv.emailRegex.MatchString(c.Email) {
+		return models.ErrEmailInvalid
+	}
+	return nil
+}
+
+// Validate the same email address isn't invited to a gallery twice
+func (cv *collaboratorValidator) notYetInvited(c *models.Collaborator) error {
+	_, err := cv.CollaboratorDBInt.ByGalleryAndEmail(c.GalleryID, c.Email)
+	switch err {
+	case models.ErrNotFound:
+		return nil
+	case nil:
+		return models.ErrCollaboratorInvited
+	default:
+		return err
+	}
+}
+
+// The owner role can't be handed out
+func (cv *collaboratorValidator) roleValid(c *models.Collaborator) error {
+	switch c.Role {
+	case models.RoleViewer, models.RoleContributor, models.RoleEditor:
+		return nil
+	}
+	return models.ErrRoleInvalid
+}
+
+func (cv *collaboratorValidator) setTokenIfUnset(c *models.Collaborator) error {
+	if c.Token != "" {
+		return nil
+	}
+	token, err := rand.RememberToken()
+	if err != nil {
+		return err
+	}
+	c.Token = token
+	return nil
+}
+
+func (cv *collaboratorValidator) hmacToken(c *models.Collaborator) error {
+	if c.Token == "" {
+		return nil
+	}
+	c.TokenHash = cv.hmac.Hash(c.Token)
+	return nil
+}
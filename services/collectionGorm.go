@@ -0,0 +1,136 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements CollectionDBInt interface
+
+type collectionGorm struct {
+	db *gorm.DB
+}
+
+func (cg *collectionGorm) ByID(id uint) (*models.Collection, error) {
+	var collection models.Collection
+	if err := first(cg.db.Where("id = ?", id), &collection); err != nil {
+		return nil, err
+	}
+	tree, err := collectionTree(cg.db, collection.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range tree {
+		if c.ID == collection.ID {
+			collection.InheritedVisibility = c.InheritedVisibility
+			collection.Depth = c.Depth
+		}
+	}
+	return &collection, nil
+}
+
+func (cg *collectionGorm) ByUserID(userID uint) ([]models.Collection, error) {
+	return collectionTree(cg.db, userID)
+}
+
+func (cg *collectionGorm) ByParentID(parentID uint) ([]models.Collection, error) {
+	parent, err := cg.ByID(parentID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := collectionTree(cg.db, parent.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var children []models.Collection
+	for _, c := range tree {
+		if c.ParentID != nil && *c.ParentID == parentID {
+			children = append(children, c)
+		}
+	}
+	return children, nil
+}
+
+func (cg *collectionGorm) Create(collection *models.Collection) error {
+	return cg.db.Create(collection).Error
+}
+
+func (cg *collectionGorm) Update(collection *models.Collection) error {
+	return cg.db.Save(collection).Error
+}
+
+func (cg *collectionGorm) Delete(id uint) error {
+	var collection models.Collection
+	if err := first(cg.db.Where("id = ?", id), &collection); err != nil {
+		return err
+	}
+	tx := cg.db.Begin()
+	err := tx.Model(&models.Gallery{}).Where("collection_id = ?", id).
+		UpdateColumn("collection_id", collection.ParentID).Error
+	if err == nil {
+		err = tx.Model(&models.Collection{}).Where("parent_id = ?", id).
+			UpdateColumn("parent_id", collection.ParentID).Error
+	}
+	if err == nil {
+		err = tx.Delete(&collection).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// collectionTree loads all of the users' collections and puts them in tree order, working out the visibility each one
+// inherits on the way down. Collections whose parent can't be found are treated as top level collections.
+func collectionTree(db *gorm.DB, userIDs ...uint) ([]models.Collection, error) {
+	var collections []models.Collection
+	if err := db.Where("user_id IN (?)", userIDs).Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return strings.ToLower(collections[i].Title) < strings.ToLower(collections[j].Title)
+	})
+	ids := make(map[uint]bool, len(collections))
+	for _, c := range collections {
+		ids[c.ID] = true
+	}
+	children := make(map[uint][]models.Collection)
+	for _, c := range collections {
+		var parentID uint
+		if c.ParentID != nil && ids[*c.ParentID] {
+			parentID = *c.ParentID
+		}
+		children[parentID] = append(children[parentID], c)
+	}
+
+	tree := make([]models.Collection, 0, len(collections))
+	var walk func(parentID uint, inherited string, depth int)
+	walk = func(parentID uint, inherited string, depth int) {
+		for _, c := range children[parentID] {
+			c.InheritedVisibility = inherited
+			c.Depth = depth
+			tree = append(tree, c)
+			walk(c.ID, c.EffectiveVisibility(), depth+1)
+		}
+	}
+	walk(0, "", 0)
+	return tree, nil
+}
+
+// collectionVisibilities returns the effective visibility of each of the users' collections, keyed by ID.
+func collectionVisibilities(db *gorm.DB, userIDs ...uint) (map[uint]string, error) {
+	tree, err := collectionTree(db, userIDs...)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[uint]string, len(tree))
+	for _, c := range tree {
+		ret[c.ID] = c.EffectiveVisibility()
+	}
+	return ret, nil
+}
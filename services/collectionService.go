@@ -0,0 +1,43 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type collectionService struct {
+	interfaces.CollectionDBInt
+}
+
+func NewCollectionService(db *gorm.DB) interfaces.CollectionServiceInt {
+	return &collectionService{
+		CollectionDBInt: &collectionValidator{
+			CollectionDBInt: &collectionGorm{db},
+		},
+	}
+}
+
+func (cs *collectionService) Breadcrumbs(id uint) ([]models.Collection, error) {
+	collection, err := cs.ByID(id)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := cs.ByUserID(collection.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Collection, len(tree))
+	for _, c := range tree {
+		byID[c.ID] = c
+	}
+	var crumbs []models.Collection
+	for c, ok := byID[id]; ok; c, ok = byID[*c.ParentID] {
+		crumbs = append([]models.Collection{c}, crumbs...)
+		if c.ParentID == nil {
+			break
+		}
+	}
+	return crumbs, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type collectionValidator struct {
+	interfaces.CollectionDBInt
+}
+
+type collectionValFn func(*models.Collection) error
+
+func (cv *collectionValidator) ByID(id uint) (*models.Collection, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return cv.CollectionDBInt.ByID(id)
+}
+
+func (cv *collectionValidator) Create(collection *models.Collection) error {
+	err := runCollectionValFns(collection,
+		cv.userIDRequired,
+		cv.titleRequired,
+		cv.defaultVisibility,
+		cv.visibilityValid,
+		cv.parentValid)
+	if err != nil {
+		return err
+	}
+	return cv.CollectionDBInt.Create(collection)
+}
+
+func (cv *collectionValidator) Update(collection *models.Collection) error {
+	err := runCollectionValFns(collection,
+		cv.userIDRequired,
+		cv.titleRequired,
+		cv.defaultVisibility,
+		cv.visibilityValid,
+		cv.parentValid)
+	if err != nil {
+		return err
+	}
+	return cv.CollectionDBInt.Update(collection)
+}
+
+func (cv *collectionValidator) Delete(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return cv.CollectionDBInt.Delete(id)
+}
+
+func runCollectionValFns(collection *models.Collection, fns ...collectionValFn) error {
+	for _, fn := range fns {
+		if err := fn(collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cv *collectionValidator) userIDRequired(c *models.Collection) error {
+	if c.UserID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (cv *collectionValidator) titleRequired(c *models.Collection) error {
+	if c.Title == "" {
+		return models.ErrTitleRequired
+	}
+	return nil
+}
+
+// Like galleries, new collections are private until their owner decides otherwise
+func (cv *collectionValidator) defaultVisibility(c *models.Collection) error {
+	if c.Visibility == "" {
+		c.Visibility = models.VisibilityPrivate
+	}
+	return nil
+}
+
+func (cv *collectionValidator) visibilityValid(c *models.Collection) error {
+	switch c.Visibility {
+	case models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic:
+		return nil
+	}
+	return models.ErrVisibilityInvalid
+}
+
+// Validate the parent belongs to the same user, and that the collection isn't being moved inside itself
+func (cv *collectionValidator) parentValid(c *models.Collection) error {
+	if c.ParentID == nil {
+		return nil
+	}
+	id := *c.ParentID
+	for {
+		if c.ID != 0 && id == c.ID {
+			return models.ErrCollectionCycle
+		}
+		parent, err := cv.CollectionDBInt.ByID(id)
+		if err == models.ErrNotFound {
+			return models.ErrCollectionInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if parent.UserID != c.UserID {
+			return models.ErrCollectionInvalid
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		id = *parent.ParentID
+	}
+}
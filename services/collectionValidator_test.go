@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+// collectionTreeDB is a CollectionDBInt that only knows how to look collections up by ID.
+type collectionTreeDB struct {
+	interfaces.CollectionDBInt
+	collections map[uint]*models.Collection
+	err         error
+}
+
+func (db *collectionTreeDB) ByID(id uint) (*models.Collection, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	if c, ok := db.collections[id]; ok {
+		return c, nil
+	}
+	return nil, models.ErrNotFound
+}
+
+func testCollection(id, userID uint, parentID *uint) *models.Collection {
+	c := &models.Collection{UserID: userID, ParentID: parentID}
+	c.ID = id
+	return c
+}
+
+func TestCollectionParentValid(t *testing.T) {
+	id := func(id uint) *uint { return &id }
+	// 1 → 2 → 3 belong to user 1, and 4 belongs to user 2
+	db := &collectionTreeDB{collections: map[uint]*models.Collection{
+		1: testCollection(1, 1, nil),
+		2: testCollection(2, 1, id(1)),
+		3: testCollection(3, 1, id(2)),
+		4: testCollection(4, 2, nil),
+	}}
+	cv := &collectionValidator{db}
+
+	tests := []struct {
+		name       string
+		collection *models.Collection
+		want       error
+	}{
+		{"top level", testCollection(1, 1, nil), nil},
+		{"new collection", testCollection(0, 1, id(3)), nil},
+		{"move up", testCollection(3, 1, id(1)), nil},
+		{"inside itself", testCollection(2, 1, id(2)), models.ErrCollectionCycle},
+		{"inside its child", testCollection(1, 1, id(2)), models.ErrCollectionCycle},
+		{"inside its grandchild", testCollection(1, 1, id(3)), models.ErrCollectionCycle},
+		{"another user's collection", testCollection(0, 1, id(4)), models.ErrCollectionInvalid},
+		{"missing collection", testCollection(0, 1, id(9)), models.ErrCollectionInvalid},
+	}
+	for _, test := range tests {
+		if err := cv.parentValid(test.collection); err != test.want {
+			t.Errorf("%s: parentValid returned %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	db.err = errors.New("connection refused")
+	if err := cv.parentValid(testCollection(0, 1, id(1))); err != db.err {
+		t.Errorf("parentValid returned %v, want the database's error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements CommentDBInt interface
+
+type commentGorm struct {
+	db *gorm.DB
+}
+
+func (cg *commentGorm) ByID(id uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := first(cg.db.Where("id = ?", id), &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (cg *commentGorm) ByGalleryID(galleryID uint, all bool) ([]models.Comment, error) {
+	var comments []models.Comment
+	db := cg.db.Where("gallery_id = ?", galleryID)
+	if !all {
+		db = db.Where("approved")
+	}
+	if err := db.Order("created_at").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (cg *commentGorm) Create(comment *models.Comment) error {
+	return cg.db.Create(comment).Error
+}
+
+func (cg *commentGorm) Update(comment *models.Comment) error {
+	return cg.db.Save(comment).Error
+}
+
+func (cg *commentGorm) Delete(id uint) error {
+	comment := models.Comment{Model: gorm.Model{ID: id}}
+	return cg.db.Delete(&comment).Error
+}
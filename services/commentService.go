@@ -0,0 +1,25 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type commentService struct {
+	interfaces.CommentDBInt
+}
+
+func NewCommentService(db *gorm.DB) interfaces.CommentServiceInt {
+	return &commentService{
+		CommentDBInt: &commentValidator{
+			CommentDBInt: &commentGorm{db},
+		},
+	}
+}
+
+func (cs *commentService) Approve(comment *models.Comment) error {
+	comment.Approved = true
+	return cs.Update(comment)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+// commentMaxLength is the longest comment, in characters, that can be left.
+const commentMaxLength = 2000
+
+type commentValidator struct {
+	interfaces.CommentDBInt
+}
+
+type commentValFn func(*models.Comment) error
+
+func (cv *commentValidator) ByID(id uint) (*models.Comment, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return cv.CommentDBInt.ByID(id)
+}
+
+func (cv *commentValidator) ByGalleryID(galleryID uint, all bool) ([]models.Comment, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return cv.CommentDBInt.ByGalleryID(galleryID, all)
+}
+
+func (cv *commentValidator) Create(comment *models.Comment) error {
+	err := runCommentValFns(comment,
+		cv.galleryIDRequired,
+		cv.filenameValid,
+		cv.normalizeName,
+		cv.nameRequired,
+		cv.normalizeBody,
+		cv.bodyRequired,
+		cv.bodyMaxLength)
+	if err != nil {
+		return err
+	}
+	return cv.CommentDBInt.Create(comment)
+}
+
+func (cv *commentValidator) Update(comment *models.Comment) error {
+	err := runCommentValFns(comment,
+		cv.galleryIDRequired,
+		cv.filenameValid,
+		cv.normalizeName,
+		cv.nameRequired,
+		cv.normalizeBody,
+		cv.bodyRequired,
+		cv.bodyMaxLength)
+	if err != nil {
+		return err
+	}
+	return cv.CommentDBInt.Update(comment)
+}
+
+func (cv *commentValidator) Delete(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return cv.CommentDBInt.Delete(id)
+}
+
+func runCommentValFns(comment *models.Comment, fns ...commentValFn) error {
+	for _, fn := range fns {
+		if err := fn(comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cv *commentValidator) galleryIDRequired(c *models.Comment) error {
+	if c.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+// filenameValid makes sure a comment on an image names a plain file within the gallery.
+func (cv *commentValidator) filenameValid(c *models.Comment) error {
+	if c.Filename != "" && c.Filename != filepath.Base(c.Filename) {
+		return models.ErrFilenameRequired
+	}
+	return nil
+}
+
+func (cv *commentValidator) normalizeName(c *models.Comment) error {
+	c.Name = strings.TrimSpace(c.Name)
+	return nil
+}
+
+func (cv *commentValidator) nameRequired(c *models.Comment) error {
+	if c.Name == "" {
+		return models.ErrNameRequired
+	}
+	return nil
+}
+
+func (cv *commentValidator) normalizeBody(c *models.Comment) error {
+	c.Body = strings.TrimSpace(c.Body)
+	return nil
+}
+
+func (cv *commentValidator) bodyRequired(c *models.Comment) error {
+	if c.Body == "" {
+		return models.ErrCommentRequired
+	}
+	return nil
+}
+
+func (cv *commentValidator) bodyMaxLength(c *models.Comment) error {
+	if utf8.RuneCountInString(c.Body) > commentMaxLength {
+		return models.ErrCommentTooLong
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements FollowDBInt interface
+
+type followGorm struct {
+	db *gorm.DB
+}
+
+func (fg *followGorm) Find(followerID, followeeID uint) (*models.Follow, error) {
+	var follow models.Follow
+	db := fg.db.Where("follower_id = ? AND followee_id = ?", followerID, followeeID)
+	if err := first(db, &follow); err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+func (fg *followGorm) CountFollowers(userID uint) (int, error) {
+	var count int
+	err := fg.db.Model(&models.Follow{}).Where("followee_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (fg *followGorm) Create(follow *models.Follow) error {
+	return fg.db.Create(follow).Error
+}
+
+// Delete removes the follow for good, so that following the same user again doesn't clash with the unique index.
+func (fg *followGorm) Delete(id uint) error {
+	follow := models.Follow{Model: gorm.Model{ID: id}}
+	return fg.db.Unscoped().Delete(&follow).Error
+}
+
+// feedImages is how many of the images added to a gallery in one day a feed item holds
+const feedImages = 4
+
+// feedUploadsSQL groups the images added to galleries into one row per gallery and day. Images added within an hour
+// of a gallery being published are left out, since they are what the gallery was published with and its own item
+// already shows them.
+const feedUploadsSQL = `
+SELECT image_details.gallery_id, COUNT(*), MIN(image_details.created_at), MAX(image_details.created_at) AS at
+FROM image_details JOIN galleries ON galleries.id = image_details.gallery_id
+WHERE image_details.deleted_at IS NULL AND image_details.gallery_id IN ?
+	AND image_details.created_at > COALESCE(galleries.publish_at, galleries.created_at) + interval '1 hour'
+GROUP BY image_details.gallery_id, date_trunc('day', image_details.created_at)`
+
+// Feed merges two kinds of item: galleries being published, which are the followed users' public galleries ordered by
+// when they were published, and images being added to those galleries, which come from when their ImageDetail was
+// created. Each kind is fetched a page past the cursor before they are merged, which is all that is needed to fill the
+// page and know whether there is another.
+func (fg *followGorm) Feed(query models.FeedQuery) (*models.FeedPage, error) {
+	var cursor *feedCursor
+	newer := query.Before != ""
+	if query.After != "" || query.Before != "" {
+		var err error
+		if cursor, err = decodeFeedCursor(query.After + query.Before); err != nil {
+			return nil, err
+		}
+	}
+	var page models.FeedPage
+	var followees []uint
+	err := fg.db.Model(&models.Follow{}).Where("follower_id = ?", query.UserID).Pluck("followee_id", &followees).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(followees) == 0 {
+		return &page, nil
+	}
+	collections, err := collectionVisibilities(fg.db, followees...)
+	if err != nil {
+		return nil, err
+	}
+	// galleries picks out the followed users' galleries that are public right now
+	galleries := func() *gorm.DB {
+		db := fg.db.Model(&models.Gallery{}).Where("user_id IN (?)", followees)
+		return whereEffectiveVisibility(db, models.VisibilityPublic, collections)
+	}
+
+	entries, err := fg.published(galleries(), collections, cursor, newer, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	uploads, err := fg.uploads(galleries().Select("id").SubQuery(), cursor, newer, query.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, uploads...)
+	// Sort away from the cursor, so that the page is the first Limit entries
+	sort.Slice(entries, func(i, j int) bool {
+		if newer {
+			return newerFeedItem(&entries[j].FeedItem, &entries[i].FeedItem)
+		}
+		return newerFeedItem(&entries[i].FeedItem, &entries[j].FeedItem)
+	})
+	more := len(entries) > query.Limit
+	if more {
+		entries = entries[:query.Limit]
+	}
+	if len(entries) == 0 {
+		return &page, nil
+	}
+	if err := fg.uploadedGalleries(entries, collections); err != nil {
+		return nil, err
+	}
+	items := make([]models.FeedItem, len(entries))
+	for i, entry := range entries {
+		if !entry.Published() {
+			if entry.Images, err = fg.uploadedImages(&entry); err != nil {
+				return nil, err
+			}
+		}
+		items[i] = entry.FeedItem
+	}
+	page.Items = items
+
+	first := encodeFeedCursor(&items[0])
+	last := encodeFeedCursor(&items[len(items)-1])
+	if newer {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		first, last = last, first
+		page.NextCursor = last
+		if more {
+			page.PrevCursor = first
+		}
+	} else {
+		if query.After != "" {
+			page.PrevCursor = first
+		}
+		if more {
+			page.NextCursor = last
+		}
+	}
+	return &page, nil
+}
+
+// feedOrder returns how to compare items with the cursor and which way to sort them, so that the items nearest the
+// cursor come first.
+func feedOrder(newer bool) (cmp, dir string) {
+	if newer {
+		return ">", "ASC"
+	}
+	return "<", "DESC"
+}
+
+// published returns up to limit items for the galleries picked out by db being published, starting from the cursor.
+func (fg *followGorm) published(db *gorm.DB, collections map[uint]string, cursor *feedCursor, newer bool,
+	limit int) ([]feedEntry, error) {
+	cmp, dir := feedOrder(newer)
+	if cursor != nil {
+		db = db.Where("(COALESCE(publish_at, created_at), id) "+cmp+" (?, ?)", cursor.At, cursor.ID)
+	}
+	db = db.Order("COALESCE(publish_at, created_at) " + dir + ", id " + dir).Limit(limit)
+	var galleries []models.Gallery
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	ret := make([]feedEntry, len(galleries))
+	for i := range galleries {
+		gallery := &galleries[i]
+		inheritVisibility(gallery, collections)
+		at := gallery.CreatedAt
+		if gallery.PublishAt != nil {
+			at = *gallery.PublishAt
+		}
+		ret[i] = feedEntry{FeedItem: models.FeedItem{Gallery: gallery, At: at}}
+	}
+	return ret, nil
+}
+
+// uploads returns up to limit items for images being added to the galleries whose IDs are selected by galleryIDs,
+// starting from the cursor. Their galleries only have their ID set; the rest is loaded by uploadedGalleries once it
+// is known which items make it onto the page.
+func (fg *followGorm) uploads(galleryIDs interface{}, cursor *feedCursor, newer bool, limit int) ([]feedEntry, error) {
+	sql := feedUploadsSQL
+	args := []interface{}{galleryIDs}
+	cmp, dir := feedOrder(newer)
+	if cursor != nil {
+		sql += " HAVING (MAX(image_details.created_at), image_details.gallery_id) " + cmp + " (?, ?)"
+		args = append(args, cursor.At, cursor.ID)
+	}
+	sql += " ORDER BY at " + dir + ", image_details.gallery_id " + dir + " LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := fg.db.Raw(sql, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []feedEntry
+	for rows.Next() {
+		var galleryID uint
+		var entry feedEntry
+		if err := rows.Scan(&galleryID, &entry.Count, &entry.since, &entry.At); err != nil {
+			return nil, err
+		}
+		entry.Gallery = &models.Gallery{Model: gorm.Model{ID: galleryID}}
+		ret = append(ret, entry)
+	}
+	return ret, rows.Err()
+}
+
+// uploadedGalleries loads the galleries of the entries for images being added, in one go.
+func (fg *followGorm) uploadedGalleries(entries []feedEntry, collections map[uint]string) error {
+	var ids []uint
+	for _, entry := range entries {
+		if !entry.Published() {
+			ids = append(ids, entry.Gallery.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var galleries []models.Gallery
+	if err := fg.db.Where("id IN (?)", ids).Find(&galleries).Error; err != nil {
+		return err
+	}
+	byID := make(map[uint]*models.Gallery, len(galleries))
+	for i := range galleries {
+		inheritVisibility(&galleries[i], collections)
+		byID[galleries[i].ID] = &galleries[i]
+	}
+	for i := range entries {
+		if gallery, ok := byID[entries[i].Gallery.ID]; ok && !entries[i].Published() {
+			entries[i].Gallery = gallery
+		}
+	}
+	return nil
+}
+
+// uploadedImages returns the most recent of the images an entry is about being added.
+func (fg *followGorm) uploadedImages(entry *feedEntry) ([]models.Image, error) {
+	var details []models.ImageDetail
+	db := fg.db.Where("gallery_id = ? AND created_at BETWEEN ? AND ?", entry.Gallery.ID, entry.since, entry.At).
+		Order("created_at DESC").Limit(feedImages)
+	if err := db.Find(&details).Error; err != nil {
+		return nil, err
+	}
+	ret := make([]models.Image, len(details))
+	for i, d := range details {
+		ret[i] = models.Image{
+			GalleryID: d.GalleryID,
+			Filename:  d.Filename,
+			Caption:   d.Caption,
+			GuestName: d.GuestName,
+			Version:   d.Version,
+			Width:     d.Width,
+			Height:    d.Height,
+		}
+	}
+	return ret, nil
+}
+
+// feedEntry is a feed item while the page is being put together. For images being added, since is when the first of
+// them was added, so that they can be looked up once the item makes it onto the page.
+type feedEntry struct {
+	models.FeedItem
+	since time.Time
+}
+
+// newerFeedItem reports whether a comes before b in a feed. Items at the same time are ordered by gallery.
+func newerFeedItem(a, b *models.FeedItem) bool {
+	if !a.At.Equal(b.At) {
+		return a.At.After(b.At)
+	}
+	return a.Gallery.ID > b.Gallery.ID
+}
+
+// feedCursor is the position of an item in a feed. It is sent to clients as base64 encoded JSON.
+type feedCursor struct {
+	At time.Time `json:"at"`
+	ID uint      `json:"id"`
+}
+
+func encodeFeedCursor(item *models.FeedItem) string {
+	b, _ := json.Marshal(feedCursor{At: item.At, ID: item.Gallery.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeFeedCursor(cursor string) (*feedCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrCursorInvalid
+	}
+	var c feedCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, models.ErrCursorInvalid
+	}
+	return &c, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"lenslocked.com/models"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	g := &models.Gallery{}
+	g.ID = 12
+	at := time.Date(2021, 9, 8, 7, 6, 5, 432100000, time.FixedZone("EST", -5*3600))
+	cursor := encodeFeedCursor(&models.FeedItem{Gallery: g, Count: 3, At: at})
+
+	c, err := decodeFeedCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeFeedCursor(%q) returned %v", cursor, err)
+	}
+	if c.ID != g.ID || !c.At.Equal(at) {
+		t.Errorf("decodeFeedCursor(%q) = %v, %d, want %v, %d", cursor, c.At, c.ID, at, g.ID)
+	}
+}
+
+func TestDecodeFeedCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"at":"yesterday","id":1}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"at":"2021-09-08T07:06:05Z","id":"1"}`)),
+	} {
+		if _, err := decodeFeedCursor(cursor); err != models.ErrCursorInvalid {
+			t.Errorf("decodeFeedCursor(%q) returned %v, want %v", cursor, err, models.ErrCursorInvalid)
+		}
+	}
+}
+
+func TestNewerFeedItem(t *testing.T) {
+	at := time.Now()
+	item := func(galleryID uint, at time.Time) *models.FeedItem {
+		g := &models.Gallery{}
+		g.ID = galleryID
+		return &models.FeedItem{Gallery: g, At: at}
+	}
+	tests := []struct {
+		a, b *models.FeedItem
+		want bool
+	}{
+		{item(1, at), item(2, at.Add(-time.Second)), true},
+		{item(2, at.Add(-time.Second)), item(1, at), false},
+		// Items at the same time are ordered by gallery, so that a cursor always points at one place in the feed
+		{item(2, at), item(1, at), true},
+		{item(1, at), item(2, at), false},
+		{item(1, at), item(1, at), false},
+	}
+	for _, test := range tests {
+		if got := newerFeedItem(test.a, test.b); got != test.want {
+			t.Errorf("newerFeedItem(gallery %d at %v, gallery %d at %v) = %v, want %v", test.a.Gallery.ID,
+				test.a.At, test.b.Gallery.ID, test.b.At, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type followService struct {
+	interfaces.FollowDBInt
+}
+
+func NewFollowService(db *gorm.DB) interfaces.FollowServiceInt {
+	return &followService{
+		FollowDBInt: &followValidator{
+			FollowDBInt: &followGorm{db},
+		},
+	}
+}
+
+func (fs *followService) Toggle(followerID, followeeID uint) (bool, error) {
+	follow, err := fs.Find(followerID, followeeID)
+	switch err {
+	case nil:
+		return false, fs.Delete(follow.ID)
+	case models.ErrNotFound:
+		follow = &models.Follow{
+			FollowerID: followerID,
+			FolloweeID: followeeID,
+		}
+		return true, fs.Create(follow)
+	default:
+		return false, err
+	}
+}
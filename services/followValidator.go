@@ -0,0 +1,85 @@
+package services
+
+import (
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type followValidator struct {
+	interfaces.FollowDBInt
+}
+
+type followValFn func(*models.Follow) error
+
+func (fv *followValidator) Find(followerID, followeeID uint) (*models.Follow, error) {
+	follow := models.Follow{FollowerID: followerID, FolloweeID: followeeID}
+	if err := runFollowValFns(&follow, fv.followerIDRequired, fv.followeeIDRequired); err != nil {
+		return nil, err
+	}
+	return fv.FollowDBInt.Find(followerID, followeeID)
+}
+
+func (fv *followValidator) CountFollowers(userID uint) (int, error) {
+	if userID <= 0 {
+		return 0, models.ErrUserIDRequired
+	}
+	return fv.FollowDBInt.CountFollowers(userID)
+}
+
+func (fv *followValidator) Feed(query models.FeedQuery) (*models.FeedPage, error) {
+	if query.UserID <= 0 {
+		return nil, models.ErrUserIDRequired
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+	return fv.FollowDBInt.Feed(query)
+}
+
+func (fv *followValidator) Create(follow *models.Follow) error {
+	err := runFollowValFns(follow, fv.followerIDRequired, fv.followeeIDRequired, fv.notSelf)
+	if err != nil {
+		return err
+	}
+	return fv.FollowDBInt.Create(follow)
+}
+
+func (fv *followValidator) Delete(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return fv.FollowDBInt.Delete(id)
+}
+
+func runFollowValFns(follow *models.Follow, fns ...followValFn) error {
+	for _, fn := range fns {
+		if err := fn(follow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fv *followValidator) followerIDRequired(f *models.Follow) error {
+	if f.FollowerID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (fv *followValidator) followeeIDRequired(f *models.Follow) error {
+	if f.FolloweeID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (fv *followValidator) notSelf(f *models.Follow) error {
+	if f.FollowerID == f.FolloweeID {
+		return models.ErrFollowSelf
+	}
+	return nil
+}
@@ -1,6 +1,14 @@
 package services
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/jinzhu/gorm"
 
 	"lenslocked.com/models"
@@ -19,27 +27,431 @@ func (gg *galleryGorm) ByID(id uint) (*models.Gallery, error) {
 	if err != nil {
 		return nil, err
 	}
+	if gallery.CollectionID != nil {
+		visibilities, err := collectionVisibilities(gg.db, gallery.UserID)
+		if err != nil {
+			return nil, err
+		}
+		inheritVisibility(&gallery, visibilities)
+	}
 	return &gallery, nil
 }
 
+func (gg *galleryGorm) BySlug(userID uint, slug string) (*models.Gallery, error) {
+	var gallery models.Gallery
+	db := gg.db.Where("user_id = ? AND slug = ?", userID, slug)
+	if err := first(db, &gallery); err != nil {
+		return nil, err
+	}
+	return gg.ByID(gallery.ID)
+}
+
+func (gg *galleryGorm) SlugTaken(userID, galleryID uint, slug string) (bool, error) {
+	return slugTaken(gg.db, userID, galleryID, slug)
+}
+
 func (gg *galleryGorm) ByUserID (userID uint) ([]models.Gallery, error) {
 	var galleries []models.Gallery
 	db := gg.db.Where("user_id = ?", userID)
 	if err := db.Find(&galleries).Error; err != nil {
 		return nil, err
 	}
+	if err := gg.inherit(userID, galleries); err != nil {
+		return nil, err
+	}
 	return galleries, nil
 }
 
+func (gg *galleryGorm) ByCollectionID(collectionID uint) ([]models.Gallery, error) {
+	var galleries []models.Gallery
+	db := gg.db.Where("collection_id = ?", collectionID).Order("lower(title), id")
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	if len(galleries) == 0 {
+		return galleries, nil
+	}
+	if err := gg.inherit(galleries[0].UserID, galleries); err != nil {
+		return nil, err
+	}
+	return galleries, nil
+}
+
+// inherit sets the visibility each of the user's galleries inherits from the collection it is in. A gallery in a
+// collection we can't place in the user's tree of collections is treated as private, to be safe.
+func (gg *galleryGorm) inherit(userID uint, galleries []models.Gallery) error {
+	inCollection := false
+	for _, g := range galleries {
+		inCollection = inCollection || g.CollectionID != nil
+	}
+	if !inCollection {
+		return nil
+	}
+	visibilities, err := collectionVisibilities(gg.db, userID)
+	if err != nil {
+		return err
+	}
+	for i := range galleries {
+		inheritVisibility(&galleries[i], visibilities)
+	}
+	return nil
+}
+
+func inheritVisibility(gallery *models.Gallery, visibilities map[uint]string) {
+	if gallery.CollectionID == nil {
+		gallery.InheritedVisibility = ""
+		return
+	}
+	v, ok := visibilities[*gallery.CollectionID]
+	if !ok {
+		v = models.VisibilityPrivate
+	}
+	gallery.InheritedVisibility = v
+}
+
+// gallerySortExprs maps each way galleries can be sorted to the column it sorts on. Titles are compared without
+// regard to case.
+var gallerySortExprs = map[string]string{
+	models.SortCreated: "created_at",
+	models.SortUpdated: "updated_at",
+	models.SortTitle:   "lower(title)",
+	models.SortImages:  "image_count",
+}
+
+// Page uses keyset pagination: rather than skipping rows with an OFFSET, a cursor records the sort value and ID of
+// the gallery at the edge of the last page and the query carries on from there. The gallery ID breaks ties so that
+// galleries with the same sort value are never skipped or repeated. One more gallery than requested is loaded to
+// find out whether there is another page.
+func (gg *galleryGorm) Page(query models.GalleryQuery) (*models.GalleryPage, error) {
+	expr := gallerySortExprs[query.Sort]
+	desc := query.Desc
+	cursor := query.After
+	if query.Before != "" {
+		// Walk backwards from the cursor, then put the page back the right way round below
+		desc = !desc
+		cursor = query.Before
+	}
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	visibilities, err := collectionVisibilities(gg.db, query.UserID)
+	if err != nil {
+		return nil, err
+	}
+	db := gg.db.Where("user_id = ?", query.UserID)
+	if query.Visibility != "" {
+		db = whereEffectiveVisibility(db, query.Visibility, visibilities)
+	}
+	if query.Tag != "" {
+		db = db.Where("? = ANY(tags)", query.Tag)
+	}
+	if cursor != "" {
+		value, id, err := decodeGalleryCursor(query.Sort, cursor)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?, ?)", expr, cmp), value, id)
+	}
+	db = db.Order(fmt.Sprintf("%s %s, id %s", expr, dir, dir)).Limit(query.Limit + 1)
+
+	var galleries []models.Gallery
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	more := len(galleries) > query.Limit
+	if more {
+		galleries = galleries[:query.Limit]
+	}
+	for i := range galleries {
+		inheritVisibility(&galleries[i], visibilities)
+	}
+	page := models.GalleryPage{Galleries: galleries}
+	if len(galleries) == 0 {
+		return &page, nil
+	}
+	first := encodeGalleryCursor(query.Sort, &galleries[0])
+	last := encodeGalleryCursor(query.Sort, &galleries[len(galleries)-1])
+	if query.Before != "" {
+		for i, j := 0, len(galleries)-1; i < j; i, j = i+1, j-1 {
+			galleries[i], galleries[j] = galleries[j], galleries[i]
+		}
+		first, last = last, first
+		page.NextCursor = last
+		if more {
+			page.PrevCursor = first
+		}
+	} else {
+		if query.After != "" {
+			page.PrevCursor = first
+		}
+		if more {
+			page.NextCursor = last
+		}
+	}
+	return &page, nil
+}
+
+// Published can't filter on the visibility galleries inherit from their collections in SQL, since that depends on each
+// owner's collections. It loads galleries that are public themselves a batch at a time instead, and keeps those that
+// are still public once their collections are taken into account.
+func (gg *galleryGorm) Published(userID uint, limit int) ([]models.Gallery, error) {
+	now := time.Now()
+	db := gg.db.Where("visibility = ?", models.VisibilityPublic).
+		Where("(publish_at IS NULL OR publish_at <= ?) AND (expire_at IS NULL OR expire_at > ?)", now, now)
+	if userID != 0 {
+		db = db.Where("user_id = ?", userID)
+	}
+	db = db.Order("COALESCE(publish_at, created_at) DESC, id DESC")
+
+	ret := make([]models.Gallery, 0, limit)
+	visibilities := make(map[uint]map[uint]string)
+	for offset := 0; len(ret) < limit; offset += limit {
+		var batch []models.Gallery
+		if err := db.Offset(offset).Limit(limit).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		for _, gallery := range batch {
+			if err := inheritOwnersVisibility(gg.db, &gallery, visibilities); err != nil {
+				return nil, err
+			}
+			if gallery.IsPublic() && len(ret) < limit {
+				ret = append(ret, gallery)
+			}
+		}
+		if len(batch) < limit {
+			break
+		}
+	}
+	return ret, nil
+}
+
+// inheritOwnersVisibility sets the visibility a gallery inherits from its collections, for lists of galleries that can
+// belong to different owners. Each owner's collections are looked up the first time one of their galleries is seen,
+// and kept in visibilities keyed by owner.
+func inheritOwnersVisibility(db *gorm.DB, gallery *models.Gallery, visibilities map[uint]map[uint]string) error {
+	if gallery.CollectionID == nil {
+		return nil
+	}
+	v, ok := visibilities[gallery.UserID]
+	if !ok {
+		var err error
+		if v, err = collectionVisibilities(db, gallery.UserID); err != nil {
+			return err
+		}
+		visibilities[gallery.UserID] = v
+	}
+	inheritVisibility(gallery, v)
+	return nil
+}
+
+// whereEffectiveVisibility filters galleries by the visibility that actually applies to them. A gallery ends up with
+// the given visibility either by having it itself while being in a collection that is at least as open, or by being
+// more open itself while being in a collection with exactly that visibility. On top of that, galleries outside their
+// publishing schedule are always private.
+func whereEffectiveVisibility(db *gorm.DB, visibility string, collections map[uint]string) *gorm.DB {
+	var asOpen, exact []uint
+	for id, v := range collections {
+		if models.AtLeastAsOpen(v, visibility) {
+			asOpen = append(asOpen, id)
+		}
+		if v == visibility {
+			exact = append(exact, id)
+		}
+	}
+	var moreOpen []string
+	for _, v := range []string{models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic} {
+		if v != visibility && models.AtLeastAsOpen(v, visibility) {
+			moreOpen = append(moreOpen, v)
+		}
+	}
+
+	where := "visibility = ? AND collection_id IS NULL"
+	args := []interface{}{visibility}
+	if len(asOpen) > 0 {
+		where = "visibility = ? AND (collection_id IS NULL OR collection_id IN (?))"
+		args = append(args, asOpen)
+	}
+	if len(exact) > 0 && len(moreOpen) > 0 {
+		where = "(" + where + ") OR (visibility IN (?) AND collection_id IN (?))"
+		args = append(args, moreOpen, exact)
+	}
+
+	// Galleries that aren't published yet, or have expired, are private whatever else they say
+	now := time.Now()
+	if visibility == models.VisibilityPrivate {
+		where = "(" + where + ") OR publish_at > ? OR expire_at <= ?"
+	} else {
+		where = "(" + where + ") AND (publish_at IS NULL OR publish_at <= ?) AND (expire_at IS NULL OR expire_at > ?)"
+	}
+	args = append(args, now, now)
+	return db.Where(where, args...)
+}
+
+// galleryCursor is the position of a gallery in a sorted list. It is sent to clients as base64 encoded JSON.
+type galleryCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeGalleryCursor(sort string, g *models.Gallery) string {
+	c := galleryCursor{ID: g.ID}
+	switch sort {
+	case models.SortCreated:
+		c.Value = g.CreatedAt.Format(time.RFC3339Nano)
+	case models.SortUpdated:
+		c.Value = g.UpdatedAt.Format(time.RFC3339Nano)
+	case models.SortTitle:
+		c.Value = strings.ToLower(g.Title)
+	case models.SortImages:
+		c.Value = strconv.Itoa(g.ImageCount)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeGalleryCursor returns the sort value held in a cursor, converted to the type of the column it is compared
+// with, and the ID of the gallery it points at.
+func decodeGalleryCursor(sort, cursor string) (interface{}, uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, models.ErrCursorInvalid
+	}
+	var c galleryCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, 0, models.ErrCursorInvalid
+	}
+	var value interface{}
+	switch sort {
+	case models.SortCreated, models.SortUpdated:
+		value, err = time.Parse(time.RFC3339Nano, c.Value)
+	case models.SortImages:
+		value, err = strconv.Atoi(c.Value)
+	default:
+		value = c.Value
+	}
+	if err != nil {
+		return nil, 0, models.ErrCursorInvalid
+	}
+	return value, c.ID, nil
+}
+
 func (gg *galleryGorm) Create(gallery *models.Gallery) error {
-	return gg.db.Create(gallery).Error
+	if err := gg.db.Create(gallery).Error; err != nil {
+		return err
+	}
+	return syncSearchVector(gg.db, gallery.ID)
 }
 
 func (gg *galleryGorm) Update(gallery *models.Gallery) error {
-	return gg.db.Save(gallery).Error
+	if err := gg.db.Save(gallery).Error; err != nil {
+		return err
+	}
+	return syncSearchVector(gg.db, gallery.ID)
 }
 
 func (gg *galleryGorm) Delete(id uint) error {
 	gallery := models.Gallery{Model: gorm.Model{ID: id}}
 	return gg.db.Delete(&gallery).Error
 }
+
+func (gg *galleryGorm) TrashedByID(id uint) (*models.Gallery, error) {
+	var gallery models.Gallery
+	db := gg.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id)
+	if err := first(db, &gallery); err != nil {
+		return nil, err
+	}
+	return &gallery, nil
+}
+
+func (gg *galleryGorm) TrashedByUserID(userID uint) ([]models.Gallery, error) {
+	var galleries []models.Gallery
+	db := gg.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC")
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	return galleries, nil
+}
+
+func (gg *galleryGorm) TrashedBefore(t time.Time) ([]models.Gallery, error) {
+	var galleries []models.Gallery
+	db := gg.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", t)
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	return galleries, nil
+}
+
+// Restore takes the gallery out of the trash. If the collection it was in has been deleted in the meantime, it is
+// taken out of that too, in the same statement so that it is never restored into a collection that is gone.
+func (gg *galleryGorm) Restore(id uint) error {
+	db := gg.db.Unscoped().Model(&models.Gallery{}).Where("id = ?", id)
+	return db.UpdateColumns(map[string]interface{}{
+		"deleted_at": gorm.Expr("NULL"),
+		"collection_id": gorm.Expr(`CASE WHEN EXISTS (
+			SELECT 1 FROM collections WHERE collections.id = galleries.collection_id AND collections.deleted_at IS NULL
+		) THEN collection_id END`),
+	}).Error
+}
+
+// galleryDependents are the models that belong to a gallery through a gallery_id column. They are removed along with
+// the gallery when it is deleted forever.
+func galleryDependents() []interface{} {
+	return []interface{}{
+		&models.Selection{},
+		&models.ShareLink{},
+		&models.Collaborator{},
+		&models.ImageDetail{},
+		&models.ImageVersion{},
+		&models.GalleryStat{},
+		&models.Comment{},
+		&models.Like{},
+		&models.Transfer{},
+		&models.GuestLink{},
+		&models.GuestUpload{},
+	}
+}
+
+func (gg *galleryGorm) DeleteForever(id uint) error {
+	tx := gg.db.Begin()
+	selections := tx.Unscoped().Table("selections").Select("id").Where("gallery_id = ?", id).SubQuery()
+	err := tx.Unscoped().Where("selection_id IN ?", selections).Delete(&models.SelectionImage{}).Error
+	for _, dependent := range galleryDependents() {
+		if err != nil {
+			break
+		}
+		err = tx.Unscoped().Where("gallery_id = ?", id).Delete(dependent).Error
+	}
+	if err == nil {
+		err = tx.Unscoped().Where("id = ?", id).Delete(&models.Gallery{}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if err := os.RemoveAll(models.GuestUploadDir(id)); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(models.ImageVersionDir(id)); err != nil {
+		return err
+	}
+	return os.RemoveAll(imageDir(id))
+}
+
+func (gg *galleryGorm) ExpiringBefore(t time.Time) ([]models.Gallery, error) {
+	var galleries []models.Gallery
+	db := gg.db.Where("notify_expiry AND expiry_notified_at IS NULL AND expire_at > ? AND expire_at <= ?", time.Now(), t)
+	if err := db.Find(&galleries).Error; err != nil {
+		return nil, err
+	}
+	return galleries, nil
+}
+
+func (gg *galleryGorm) MarkExpiryNotified(id uint) error {
+	db := gg.db.Model(&models.Gallery{}).Where("id = ?", id)
+	return db.UpdateColumn("expiry_notified_at", time.Now()).Error
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"lenslocked.com/models"
+)
+
+func TestGalleryCursorRoundTrip(t *testing.T) {
+	created := time.Date(2021, 3, 4, 5, 6, 7, 891011121, time.UTC)
+	updated := time.Date(2022, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	g := &models.Gallery{Title: "Summer in ROME", ImageCount: 42}
+	g.ID = 17
+	g.CreatedAt = created
+	g.UpdatedAt = updated
+
+	tests := []struct {
+		sort string
+		want interface{}
+	}{
+		{models.SortCreated, created},
+		{models.SortUpdated, updated},
+		{models.SortTitle, "summer in rome"},
+		{models.SortImages, 42},
+	}
+	for _, test := range tests {
+		cursor := encodeGalleryCursor(test.sort, g)
+		value, id, err := decodeGalleryCursor(test.sort, cursor)
+		if err != nil {
+			t.Errorf("decodeGalleryCursor(%q, %q) returned %v", test.sort, cursor, err)
+			continue
+		}
+		if id != g.ID {
+			t.Errorf("decodeGalleryCursor(%q, %q) ID = %d, want %d", test.sort, cursor, id, g.ID)
+		}
+		if want, ok := test.want.(time.Time); ok {
+			if got, ok := value.(time.Time); !ok || !got.Equal(want) {
+				t.Errorf("decodeGalleryCursor(%q, %q) value = %v, want %v", test.sort, cursor, value, want)
+			}
+		} else if value != test.want {
+			t.Errorf("decodeGalleryCursor(%q, %q) value = %#v, want %#v", test.sort, cursor, value, test.want)
+		}
+	}
+}
+
+func TestDecodeGalleryCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		sort   string
+		cursor string
+	}{
+		{models.SortCreated, "not base64!"},
+		{models.SortCreated, encode("not json")},
+		{models.SortCreated, encode(`{"v":"yesterday","id":1}`)},
+		{models.SortUpdated, encode(`{"v":"","id":1}`)},
+		{models.SortImages, encode(`{"v":"many","id":1}`)},
+		{models.SortTitle, encode(`{"v":"rome","id":-1}`)},
+		{models.SortTitle, encode(`{"v":3,"id":1}`)},
+	}
+	for _, test := range tests {
+		if _, _, err := decodeGalleryCursor(test.sort, test.cursor); err != models.ErrCursorInvalid {
+			t.Errorf("decodeGalleryCursor(%q, %q) returned %v, want %v", test.sort, test.cursor, err,
+				models.ErrCursorInvalid)
+		}
+	}
+}
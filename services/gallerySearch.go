@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Galleries are searched through galleries.search_vector, a tsvector of each gallery's title, description, tags and
+// image captions, weighted in that order. It can't be a generated column because the captions live in image_details,
+// so like image_count it is kept up to date by syncSearchVector whenever any of them change.
+
+// searchVectorSQL sets search_vector for the galleries picked out by the WHERE clause that is added to it.
+const searchVectorSQL = `
+UPDATE galleries SET search_vector =
+	setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+	setweight(to_tsvector('english', coalesce((
+		SELECT string_agg(image_details.caption, ' ')
+		FROM image_details
+		WHERE image_details.gallery_id = galleries.id AND image_details.deleted_at IS NULL
+	), '')), 'C')
+`
+
+// syncSearchVector brings a gallery's search_vector up to date.
+func syncSearchVector(db *gorm.DB, galleryID uint) error {
+	return db.Exec(searchVectorSQL+"WHERE id = ?", galleryID).Error
+}
+
+// syncSearchVectors fills in search_vector for galleries that were created before it was stored.
+func syncSearchVectors(db *gorm.DB) error {
+	return db.Exec(searchVectorSQL + "WHERE search_vector IS NULL").Error
+}
+
+// maxSearchResults caps how many galleries a search returns
+const maxSearchResults = 50
+
+// gallerySearchSQL matches a plain text query against each gallery's search_vector and ranks the matches.
+// ts_headline marks matching words with models.HighlightStart and models.HighlightStop; the captions it needs for that
+// are only gathered for the galleries that matched.
+const gallerySearchSQL = `
+SELECT galleries.*,
+	ts_rank(galleries.search_vector, query) AS rank,
+	ts_headline('english',
+		concat_ws(' ', galleries.title, array_to_string(galleries.tags, ' '), galleries.description, captions.text),
+		query, ?) AS headline
+FROM galleries
+	CROSS JOIN plainto_tsquery('english', ?) AS query
+	LEFT JOIN LATERAL (
+		SELECT string_agg(image_details.caption, ' ') AS text
+		FROM image_details
+		WHERE image_details.gallery_id = galleries.id AND image_details.deleted_at IS NULL
+	) AS captions ON true
+WHERE galleries.user_id = ? AND galleries.deleted_at IS NULL AND galleries.search_vector @@ query
+ORDER BY rank DESC, galleries.id DESC
+LIMIT ?`
+
+func (gg *galleryGorm) Search(userID uint, query string) ([]models.GallerySearchResult, error) {
+	var results []models.GallerySearchResult
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2`,
+		models.HighlightStart, models.HighlightStop)
+	db := gg.db.Raw(gallerySearchSQL, options, query, userID, maxSearchResults)
+	if err := db.Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
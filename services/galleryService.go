@@ -1,21 +1,88 @@
 package services
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+
 	"github.com/jinzhu/gorm"
+	"golang.org/x/crypto/bcrypt"
 
+	"lenslocked.com/hash"
 	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
 )
 
 type galleryService struct {
 	interfaces.GalleryDBInt
+	pepper    string
+	hmac      hash.HMAC
+	retention time.Duration
 }
 
-func NewGalleryService(db *gorm.DB) interfaces.GalleryServiceInt {
+func NewGalleryService(db *gorm.DB, pepper, hmacKey string, retention time.Duration) interfaces.GalleryServiceInt {
 	return &galleryService{
 		GalleryDBInt: &galleryValidator{
 			GalleryDBInt: &galleryGorm{
 				db: db,
 			},
+			pepper: pepper,
 		},
+		pepper:    pepper,
+		hmac:      hash.NewHMAC(hmacKey),
+		retention: retention,
+	}
+}
+
+func (gs *galleryService) Unlock(gallery *models.Gallery, password string) (string, error) {
+	if !gallery.HasPassword() {
+		return "", models.ErrPasswordRequired
+	}
+	err := bcrypt.CompareHashAndPassword(
+		[]byte(gallery.PasswordHash),
+		[]byte(password+gs.pepper))
+	switch err {
+	case nil:
+		return gs.unlockToken(gallery), nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return "", models.ErrPasswordIncorrect
+	default:
+		return "", err
+	}
+}
+
+func (gs *galleryService) Unlocked(gallery *models.Gallery, token string) bool {
+	if !gallery.HasPassword() || token == "" {
+		return false
+	}
+	expected := gs.unlockToken(gallery)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+// unlockToken signs the gallery ID together with its password hash, so a token only ever works for one gallery and
+// stops working as soon as the owner changes the password.
+func (gs *galleryService) unlockToken(gallery *models.Gallery) string {
+	return gs.hmac.Hash(fmt.Sprintf("gallery:%d:%s", gallery.ID, gallery.PasswordHash))
+}
+
+func (gs *galleryService) PurgeAt(gallery *models.Gallery) time.Time {
+	if gallery.DeletedAt == nil {
+		return time.Time{}
+	}
+	return gallery.DeletedAt.Add(gs.retention)
+}
+
+func (gs *galleryService) PurgeTrash() (int, error) {
+	galleries, err := gs.TrashedBefore(time.Now().Add(-gs.retention))
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, gallery := range galleries {
+		if err := gs.DeleteForever(gallery.ID); err != nil {
+			return purged, err
+		}
+		purged++
 	}
+	return purged, nil
 }
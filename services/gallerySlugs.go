@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// defaultSlug is used for galleries whose title gives an empty slug.
+const defaultSlug = "gallery"
+
+// slugTaken reports whether another of the user's galleries has the slug. Galleries in the trash keep their slugs so
+// that they can be restored at the same address.
+func slugTaken(db *gorm.DB, userID, galleryID uint, slug string) (bool, error) {
+	var count int
+	err := db.Unscoped().Model(&models.Gallery{}).
+		Where("user_id = ? AND slug = ? AND id <> ?", userID, slug, galleryID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// freeSlug makes a slug from the title that isn't taken yet by numbering it, e.g. "rome", "rome-2", "rome-3".
+func freeSlug(title string, taken func(slug string) (bool, error)) (string, error) {
+	base := models.Slugify(title)
+	if base == "" {
+		base = defaultSlug
+	}
+	slug := base
+	for n := 2; ; n++ {
+		isTaken, err := taken(slug)
+		if err != nil || !isTaken {
+			return slug, err
+		}
+		slug = base + "-" + strconv.Itoa(n)
+	}
+}
+
+// syncSlugs gives a slug to galleries that were created before galleries had them.
+func syncSlugs(db *gorm.DB) error {
+	var galleries []models.Gallery
+	err := db.Unscoped().Select("id, user_id, title").Where("slug = ''").Find(&galleries).Error
+	if err != nil {
+		return err
+	}
+	for _, g := range galleries {
+		slug, err := freeSlug(g.Title, func(slug string) (bool, error) {
+			return slugTaken(db, g.UserID, g.ID, slug)
+		})
+		if err != nil {
+			return err
+		}
+		err = db.Unscoped().Model(&models.Gallery{}).Where("id = ?", g.ID).UpdateColumn("slug", slug).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFreeSlug(t *testing.T) {
+	taken := map[string]bool{"rome": true, "rome-2": true, "gallery": true}
+	isTaken := func(slug string) (bool, error) {
+		return taken[slug], nil
+	}
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Paris", "paris"},
+		{"Rome", "rome-3"},
+		{"!!!", "gallery-2"},
+	}
+	for _, test := range tests {
+		slug, err := freeSlug(test.title, isTaken)
+		if err != nil || slug != test.want {
+			t.Errorf("freeSlug(%q) = %q, %v, want %q, nil", test.title, slug, err, test.want)
+		}
+	}
+
+	want := errors.New("connection refused")
+	if _, err := freeSlug("Rome", func(string) (bool, error) { return false, want }); err != want {
+		t.Errorf("freeSlug returned %v, want the error from taken", err)
+	}
+}
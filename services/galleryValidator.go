@@ -1,12 +1,18 @@
 package services
 
 import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
 	"lenslocked.com/interfaces"
 	"lenslocked.com/models"
 )
 
 type galleryValidator struct {
 	interfaces.GalleryDBInt
+	pepper string
 }
 
 type galleryValFn func(*models.Gallery) error
@@ -14,7 +20,19 @@ type galleryValFn func(*models.Gallery) error
 func (gv *galleryValidator) Create(gallery *models.Gallery) error {
 	err := runGalleryValFns(gallery,
 		gv.userIDRequired,
-		gv.titleRequired)
+		gv.titleRequired,
+		gv.setSlug,
+		gv.descriptionMaxLength,
+		gv.defaultVisibility,
+		gv.visibilityValid,
+		gv.expiryAfterPublish,
+		gv.defaultCommentMode,
+		gv.commentModeValid,
+		gv.defaultLayout,
+		gv.layoutValid,
+		gv.passwordMinLength,
+		gv.bcryptPassword,
+		gv.normalizeTags)
 	if err != nil {
 		return err
 	}
@@ -24,7 +42,19 @@ func (gv *galleryValidator) Create(gallery *models.Gallery) error {
 func (gv *galleryValidator) Update(gallery *models.Gallery) error {
 	err := runGalleryValFns(gallery,
 		gv.userIDRequired,
-		gv.titleRequired)
+		gv.titleRequired,
+		gv.setSlug,
+		gv.descriptionMaxLength,
+		gv.defaultVisibility,
+		gv.visibilityValid,
+		gv.expiryAfterPublish,
+		gv.defaultCommentMode,
+		gv.commentModeValid,
+		gv.defaultLayout,
+		gv.layoutValid,
+		gv.passwordMinLength,
+		gv.bcryptPassword,
+		gv.normalizeTags)
 	if err != nil {
 		return err
 	}
@@ -40,6 +70,82 @@ func (gv *galleryValidator) Delete(id uint) error {
 	return gv.GalleryDBInt.Delete(gallery.ID)
 }
 
+func (gv *galleryValidator) TrashedByID(id uint) (*models.Gallery, error) {
+	var gallery models.Gallery
+	gallery.ID = id
+	if err := runGalleryValFns(&gallery, gv.nonZeroID); err != nil {
+		return nil, err
+	}
+	return gv.GalleryDBInt.TrashedByID(gallery.ID)
+}
+
+func (gv *galleryValidator) TrashedByUserID(userID uint) ([]models.Gallery, error) {
+	if userID <= 0 {
+		return nil, models.ErrUserIDRequired
+	}
+	return gv.GalleryDBInt.TrashedByUserID(userID)
+}
+
+func (gv *galleryValidator) Restore(id uint) error {
+	var gallery models.Gallery
+	gallery.ID = id
+	if err := runGalleryValFns(&gallery, gv.nonZeroID); err != nil {
+		return err
+	}
+	return gv.GalleryDBInt.Restore(gallery.ID)
+}
+
+func (gv *galleryValidator) DeleteForever(id uint) error {
+	var gallery models.Gallery
+	gallery.ID = id
+	if err := runGalleryValFns(&gallery, gv.nonZeroID); err != nil {
+		return err
+	}
+	return gv.GalleryDBInt.DeleteForever(gallery.ID)
+}
+
+func (gv *galleryValidator) Search(userID uint, query string) ([]models.GallerySearchResult, error) {
+	if userID <= 0 {
+		return nil, models.ErrUserIDRequired
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	return gv.GalleryDBInt.Search(userID, query)
+}
+
+// Page sizes for the galleries index
+const (
+	defaultPageSize = 24
+	maxPageSize     = 96
+)
+
+func (gv *galleryValidator) Page(query models.GalleryQuery) (*models.GalleryPage, error) {
+	if query.UserID <= 0 {
+		return nil, models.ErrUserIDRequired
+	}
+	if query.Sort == "" {
+		query.Sort = models.SortCreated
+	}
+	if _, ok := gallerySortExprs[query.Sort]; !ok {
+		return nil, models.ErrSortInvalid
+	}
+	if query.Visibility != "" {
+		if err := gv.visibilityValid(&models.Gallery{Visibility: query.Visibility}); err != nil {
+			return nil, err
+		}
+	}
+	query.Tag = strings.ToLower(strings.TrimSpace(query.Tag))
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+	return gv.GalleryDBInt.Page(query)
+}
+
 func runGalleryValFns(gallery *models.Gallery, fns ...galleryValFn) error {
 	for _, fn := range fns {
 		if err := fn(gallery); err != nil {
@@ -63,6 +169,140 @@ func (gv *galleryValidator) titleRequired(g *models.Gallery) error {
 	return nil
 }
 
+// maxDescriptionLength is the most characters a gallery description can have
+const maxDescriptionLength = 10000
+
+func (gv *galleryValidator) descriptionMaxLength(g *models.Gallery) error {
+	if utf8.RuneCountInString(g.Description) > maxDescriptionLength {
+		return models.ErrDescriptionLong
+	}
+	return nil
+}
+
+// New galleries are private until their owner decides otherwise
+func (gv *galleryValidator) defaultVisibility(g *models.Gallery) error {
+	if g.Visibility == "" {
+		g.Visibility = models.VisibilityPrivate
+	}
+	return nil
+}
+
+func (gv *galleryValidator) visibilityValid(g *models.Gallery) error {
+	switch g.Visibility {
+	case models.VisibilityPrivate, models.VisibilityUnlisted, models.VisibilityPublic:
+		return nil
+	}
+	return models.ErrVisibilityInvalid
+}
+
+func (gv *galleryValidator) expiryAfterPublish(g *models.Gallery) error {
+	if g.PublishAt != nil && g.ExpireAt != nil && !g.ExpireAt.After(*g.PublishAt) {
+		return models.ErrExpiryBeforePub
+	}
+	return nil
+}
+
+func (gv *galleryValidator) defaultCommentMode(g *models.Gallery) error {
+	if g.CommentMode == "" {
+		g.CommentMode = models.CommentsOff
+	}
+	return nil
+}
+
+func (gv *galleryValidator) commentModeValid(g *models.Gallery) error {
+	switch g.CommentMode {
+	case models.CommentsOff, models.CommentsModerated, models.CommentsOpen:
+		return nil
+	}
+	return models.ErrCommentModeInvalid
+}
+
+func (gv *galleryValidator) defaultLayout(g *models.Gallery) error {
+	if g.Layout == "" {
+		g.Layout = models.LayoutGrid
+	}
+	return nil
+}
+
+// Only masonry layouts have columns, but the column count is kept when switching layouts so it isn't lost on the way
+// back
+func (gv *galleryValidator) layoutValid(g *models.Gallery) error {
+	switch g.Layout {
+	case models.LayoutGrid, models.LayoutMasonry, models.LayoutJustified, models.LayoutStory:
+	default:
+		return models.ErrLayoutInvalid
+	}
+	if g.Columns != 0 && (g.Columns < models.MinColumns || g.Columns > models.MaxColumns) {
+		return models.ErrColumnsInvalid
+	}
+	return nil
+}
+
+// Validate the length of a new gallery password
+func (gv *galleryValidator) passwordMinLength(g *models.Gallery) error {
+	if g.Password == "" {
+		return nil
+	}
+	if len(g.Password) < 8 {
+		return models.ErrPasswordTooShort
+	}
+	return nil
+}
+
+// Hashes a new gallery password the same way user passwords are hashed
+func (gv *galleryValidator) bcryptPassword(g *models.Gallery) error {
+	if g.Password == "" {
+		// The password hasn't been changed
+		return nil
+	}
+	pwBytes := []byte(g.Password + gv.pepper)
+	hashedBytes, err := bcrypt.GenerateFromPassword(pwBytes,
+		bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	g.PasswordHash = string(hashedBytes)
+	g.Password = ""
+	return nil
+}
+
+// Slugs are made from the title when the owner hasn't picked one, and numbered to keep them unique. A slug the owner
+// has picked is tidied up the same way, but is never renumbered behind their back.
+func (gv *galleryValidator) setSlug(g *models.Gallery) error {
+	g.Slug = models.Slugify(g.Slug)
+	if g.Slug == "" {
+		slug, err := freeSlug(g.Title, func(slug string) (bool, error) {
+			return gv.GalleryDBInt.SlugTaken(g.UserID, g.ID, slug)
+		})
+		g.Slug = slug
+		return err
+	}
+	taken, err := gv.GalleryDBInt.SlugTaken(g.UserID, g.ID, g.Slug)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return models.ErrSlugTaken
+	}
+	return nil
+}
+
+// Tags are trimmed, lowercased and de-duplicated so that "Wedding" and "wedding " are the same tag
+func (gv *galleryValidator) normalizeTags(g *models.Gallery) error {
+	seen := make(map[string]bool, len(g.Tags))
+	tags := make([]string, 0, len(g.Tags))
+	for _, tag := range g.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	g.Tags = tags
+	return nil
+}
+
 func (gv *galleryValidator) nonZeroID(gallery *models.Gallery) error {
 	if gallery.ID <= 0 {
 		return models.ErrIDInvalid
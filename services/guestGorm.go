@@ -0,0 +1,107 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements GuestDBInt interface
+
+type guestGorm struct {
+	db *gorm.DB
+}
+
+func (gg *guestGorm) LinkByID(id uint) (*models.GuestLink, error) {
+	var link models.GuestLink
+	err := first(gg.db.Where("id = ?", id), &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (gg *guestGorm) LinkByToken(tokenHash string) (*models.GuestLink, error) {
+	var link models.GuestLink
+	err := first(gg.db.Where("token_hash = ?", tokenHash), &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (gg *guestGorm) LinksByGalleryID(galleryID uint) ([]models.GuestLink, error) {
+	var links []models.GuestLink
+	db := gg.db.Where("gallery_id = ?", galleryID).Order("created_at desc")
+	if err := db.Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (gg *guestGorm) CreateLink(link *models.GuestLink) error {
+	return gg.db.Create(link).Error
+}
+
+func (gg *guestGorm) UpdateLink(link *models.GuestLink) error {
+	return gg.db.Save(link).Error
+}
+
+// RevokeLink only sets revoked_at, so that it can't write back a stale upload count.
+func (gg *guestGorm) RevokeLink(link *models.GuestLink) error {
+	if link.Revoked() {
+		return nil
+	}
+	now := time.Now()
+	err := gg.db.Model(link).Where("revoked_at IS NULL").UpdateColumn("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	link.RevokedAt = &now
+	return nil
+}
+
+// ClaimUpload checks the limit and counts the upload in one statement, so that guests uploading at the same time
+// can't go over it between them, and an upload that finishes after the link was revoked isn't counted.
+func (gg *guestGorm) ClaimUpload(link *models.GuestLink) error {
+	db := gg.db.Model(link).Where("revoked_at IS NULL AND (max_uploads = 0 OR uploads < max_uploads)").
+		UpdateColumn("uploads", gorm.Expr("uploads + 1"))
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return models.ErrGuestUploadsUsed
+	}
+	link.Uploads++
+	return nil
+}
+
+func (gg *guestGorm) UploadByID(id uint) (*models.GuestUpload, error) {
+	var upload models.GuestUpload
+	err := first(gg.db.Where("id = ?", id), &upload)
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (gg *guestGorm) Pending(galleryID uint) ([]models.GuestUpload, error) {
+	var uploads []models.GuestUpload
+	db := gg.db.Where("gallery_id = ?", galleryID).Order("created_at, id")
+	if err := db.Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func (gg *guestGorm) CreateUpload(upload *models.GuestUpload) error {
+	return gg.db.Create(upload).Error
+}
+
+// DeleteUpload removes the upload for good, since its file is gone once it has been approved or rejected.
+func (gg *guestGorm) DeleteUpload(id uint) error {
+	upload := models.GuestUpload{Model: gorm.Model{ID: id}}
+	return gg.db.Unscoped().Delete(&upload).Error
+}
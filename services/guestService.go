@@ -0,0 +1,129 @@
+package services
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type guestService struct {
+	interfaces.GuestDBInt
+	db *gorm.DB
+}
+
+func NewGuestService(db *gorm.DB, hmacKey string) interfaces.GuestServiceInt {
+	return &guestService{
+		GuestDBInt: &guestValidator{
+			GuestDBInt: &guestGorm{db},
+			hmac:       hash.NewHMAC(hmacKey),
+		},
+		db: db,
+	}
+}
+
+// Upload creates the upload first, both to validate it and to get the ID its file is stored under. The upload is only
+// counted against the link once the whole photo has been stored within the size limit, and is deleted again if it
+// doesn't make it that far.
+func (gs *guestService) Upload(link *models.GuestLink, guestName string, r io.Reader,
+	filename string) (*models.GuestUpload, error) {
+	if link.Revoked() {
+		return nil, models.ErrGuestLinkInvalid
+	}
+	if link.UploadsExhausted() {
+		return nil, models.ErrGuestUploadsUsed
+	}
+	upload := models.GuestUpload{
+		GalleryID:   link.GalleryID,
+		GuestLinkID: link.ID,
+		GuestName:   guestName,
+		Filename:    filename,
+	}
+	if err := gs.CreateUpload(&upload); err != nil {
+		return nil, err
+	}
+	err := gs.store(&upload, r, link.MaxBytes())
+	if err == nil {
+		err = gs.ClaimUpload(link)
+	}
+	if err != nil {
+		os.Remove(upload.RelativePath())
+		gs.DeleteUpload(upload.ID)
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// store writes the upload's file, failing with ErrGuestImageTooBig if it is over maxBytes.
+func (gs *guestService) store(upload *models.GuestUpload, r io.Reader, maxBytes int64) error {
+	if err := os.MkdirAll(models.GuestUploadDir(upload.GalleryID), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(upload.RelativePath())
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	// Read one byte past the limit to find out whether the photo is over it
+	n, err := io.Copy(dst, io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return err
+	}
+	if n > maxBytes {
+		return models.ErrGuestImageTooBig
+	}
+	return nil
+}
+
+// Approve moves the upload's file into the gallery, renaming it if the gallery already has an image with the same
+// filename, and records the guest's name and the photo's size in the image's details.
+func (gs *guestService) Approve(upload *models.GuestUpload) error {
+	dir := imageDir(upload.GalleryID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	filename := freeFilename(dir, upload.Filename)
+	if err := os.Rename(upload.RelativePath(), filepath.Join(dir, filename)); err != nil {
+		return err
+	}
+	var detail models.ImageDetail
+	err := gs.db.Where(models.ImageDetail{GalleryID: upload.GalleryID, Filename: filename}).
+		Assign(models.ImageDetail{GuestName: upload.GuestName}).FirstOrCreate(&detail).Error
+	if err != nil {
+		return err
+	}
+	if err := setImageSize(gs.db, upload.GalleryID, filename); err != nil {
+		return err
+	}
+	if err := syncImageCount(gs.db, upload.GalleryID); err != nil {
+		return err
+	}
+	return gs.DeleteUpload(upload.ID)
+}
+
+// freeFilename returns filename, or if dir already has a file by that name, the filename with the first free number
+// added before its extension.
+func freeFilename(dir, filename string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(dir, filename)); os.IsNotExist(err) {
+			return filename
+		}
+		filename = base + "-" + strconv.Itoa(n) + ext
+	}
+}
+
+func (gs *guestService) Reject(upload *models.GuestUpload) error {
+	if err := os.Remove(upload.RelativePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return gs.DeleteUpload(upload.ID)
+}
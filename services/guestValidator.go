@@ -0,0 +1,222 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/rand"
+)
+
+// maxGuestNameLength is the longest name a guest can leave with their photos
+const maxGuestNameLength = 100
+
+type guestValidator struct {
+	interfaces.GuestDBInt
+	hmac hash.HMAC
+}
+
+type guestLinkValFn func(*models.GuestLink) error
+
+type guestUploadValFn func(*models.GuestUpload) error
+
+func (gv *guestValidator) LinkByID(id uint) (*models.GuestLink, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return gv.GuestDBInt.LinkByID(id)
+}
+
+func (gv *guestValidator) LinkByToken(token string) (*models.GuestLink, error) {
+	link := models.GuestLink{Token: token}
+	if err := runGuestLinkValFns(&link, gv.hmacToken); err != nil {
+		return nil, err
+	}
+	return gv.GuestDBInt.LinkByToken(link.TokenHash)
+}
+
+func (gv *guestValidator) LinksByGalleryID(galleryID uint) ([]models.GuestLink, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return gv.GuestDBInt.LinksByGalleryID(galleryID)
+}
+
+func (gv *guestValidator) CreateLink(link *models.GuestLink) error {
+	err := runGuestLinkValFns(link,
+		gv.galleryIDRequired,
+		gv.maxUploadsNonNegative,
+		gv.defaultMaxSize,
+		gv.maxSizeValid,
+		gv.setTokenIfUnset,
+		gv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return gv.GuestDBInt.CreateLink(link)
+}
+
+func (gv *guestValidator) UpdateLink(link *models.GuestLink) error {
+	err := runGuestLinkValFns(link,
+		gv.galleryIDRequired,
+		gv.maxUploadsNonNegative,
+		gv.maxSizeValid,
+		gv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return gv.GuestDBInt.UpdateLink(link)
+}
+
+func (gv *guestValidator) ClaimUpload(link *models.GuestLink) error {
+	if link.ID <= 0 {
+		return models.ErrIDInvalid
+	}
+	return gv.GuestDBInt.ClaimUpload(link)
+}
+
+func (gv *guestValidator) UploadByID(id uint) (*models.GuestUpload, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return gv.GuestDBInt.UploadByID(id)
+}
+
+func (gv *guestValidator) Pending(galleryID uint) ([]models.GuestUpload, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return gv.GuestDBInt.Pending(galleryID)
+}
+
+func (gv *guestValidator) CreateUpload(upload *models.GuestUpload) error {
+	err := runGuestUploadValFns(upload,
+		gv.uploadGalleryIDRequired,
+		gv.guestLinkIDRequired,
+		gv.normalizeGuestName,
+		gv.guestNameRequired,
+		gv.guestNameLength,
+		gv.filenameImage)
+	if err != nil {
+		return err
+	}
+	return gv.GuestDBInt.CreateUpload(upload)
+}
+
+func (gv *guestValidator) DeleteUpload(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return gv.GuestDBInt.DeleteUpload(id)
+}
+
+func runGuestLinkValFns(link *models.GuestLink, fns ...guestLinkValFn) error {
+	for _, fn := range fns {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runGuestUploadValFns(upload *models.GuestUpload, fns ...guestUploadValFn) error {
+	for _, fn := range fns {
+		if err := fn(upload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (gv *guestValidator) galleryIDRequired(link *models.GuestLink) error {
+	if link.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (gv *guestValidator) maxUploadsNonNegative(link *models.GuestLink) error {
+	if link.MaxUploads < 0 {
+		return models.ErrMaxUploadsInvalid
+	}
+	return nil
+}
+
+func (gv *guestValidator) defaultMaxSize(link *models.GuestLink) error {
+	if link.MaxSizeMB == 0 {
+		link.MaxSizeMB = models.DefaultGuestSizeMB
+	}
+	return nil
+}
+
+func (gv *guestValidator) maxSizeValid(link *models.GuestLink) error {
+	if link.MaxSizeMB < 1 || link.MaxSizeMB > models.MaxGuestSizeMB {
+		return models.ErrMaxSizeInvalid
+	}
+	return nil
+}
+
+func (gv *guestValidator) setTokenIfUnset(link *models.GuestLink) error {
+	if link.Token != "" {
+		return nil
+	}
+	token, err := rand.RememberToken()
+	if err != nil {
+		return err
+	}
+	link.Token = token
+	return nil
+}
+
+func (gv *guestValidator) hmacToken(link *models.GuestLink) error {
+	if link.Token == "" {
+		return nil
+	}
+	link.TokenHash = gv.hmac.Hash(link.Token)
+	return nil
+}
+
+func (gv *guestValidator) uploadGalleryIDRequired(upload *models.GuestUpload) error {
+	if upload.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (gv *guestValidator) guestLinkIDRequired(upload *models.GuestUpload) error {
+	if upload.GuestLinkID <= 0 {
+		return models.ErrGuestLinkInvalid
+	}
+	return nil
+}
+
+func (gv *guestValidator) normalizeGuestName(upload *models.GuestUpload) error {
+	upload.GuestName = strings.TrimSpace(upload.GuestName)
+	return nil
+}
+
+func (gv *guestValidator) guestNameRequired(upload *models.GuestUpload) error {
+	if upload.GuestName == "" {
+		return models.ErrNameRequired
+	}
+	return nil
+}
+
+func (gv *guestValidator) guestNameLength(upload *models.GuestUpload) error {
+	if utf8.RuneCountInString(upload.GuestName) > maxGuestNameLength {
+		return models.ErrGuestNameTooLong
+	}
+	return nil
+}
+
+// filenameImage makes sure the upload is a plain file named like a JPEG, PNG or GIF image.
+func (gv *guestValidator) filenameImage(upload *models.GuestUpload) error {
+	upload.Filename = filepath.Base(upload.Filename)
+	if strings.HasPrefix(upload.Filename, ".") || imageFormats[strings.ToLower(filepath.Ext(upload.Filename))] == "" {
+		return models.ErrGuestImageInvalid
+	}
+	return nil
+}
@@ -2,18 +2,31 @@ package services
 
 import (
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/jinzhu/gorm"
 
 	"lenslocked.com/interfaces"
 	"lenslocked.com/models"
 )
 
-type imageService struct{}
+// imageService stores image files on disk, and anything else we know about them (see models.ImageDetail) in the
+// database.
+type imageService struct {
+	db *gorm.DB
+}
 
-func NewImageService() interfaces.ImageServiceInt {
-	return &imageService{}
+func NewImageService(db *gorm.DB) interfaces.ImageServiceInt {
+	return &imageService{
+		db: db,
+	}
 }
 
 // Create accepts the data to create an image - any type that satisfies the io.Reader interface, which is any type that
@@ -35,7 +48,11 @@ func (is *imageService) Create(galleryID uint, r io.Reader, filename string) err
 	if err != nil {
 		return err
 	}
-	return nil
+	// The detail's CreatedAt records when the image was added, which is what followers' feeds show
+	if err := setImageSize(is.db, galleryID, filename); err != nil {
+		return err
+	}
+	return syncImageCount(is.db, galleryID)
 }
 
 func (is *imageService) ByGalleryID(galleryID uint) ([]models.Image, error) {
@@ -44,18 +61,122 @@ func (is *imageService) ByGalleryID(galleryID uint) ([]models.Image, error) {
 	if err != nil {
 		return nil, err
 	}
+	details, err := is.details(galleryID)
+	if err != nil {
+		return nil, err
+	}
 	// Setup the Image slice we are returning
 	ret := make([]models.Image, len(strings))
 	for i, imgStr := range strings {
+		filename := filepath.Base(imgStr)
 		ret[i] = models.Image{
-			Filename:  filepath.Base(imgStr),
+			Filename:  filename,
 			GalleryID: galleryID,
+			Caption:   details[filename].Caption,
+			GuestName: details[filename].GuestName,
+			Version:   details[filename].Version,
+			Width:     details[filename].Width,
+			Height:    details[filename].Height,
 		}
 	}
 	return ret, nil
 }
 
+// imageSize reads the width and height of an image from its header, without decoding the rest of it. Files that aren't
+// JPEG, PNG or GIF images have a size of 0 by 0.
+func imageSize(path string) (int, int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
+// setImageSize reads an image's width and height from its file and stores them in its details, creating them if the
+// image has none yet.
+func setImageSize(db *gorm.DB, galleryID uint, filename string) error {
+	width, height := imageSize(filepath.Join(imageDir(galleryID), filename))
+	var detail models.ImageDetail
+	return db.Where(models.ImageDetail{GalleryID: galleryID, Filename: filename}).
+		Assign(map[string]interface{}{"width": width, "height": height}).
+		FirstOrCreate(&detail).Error
+}
+
+// syncImageSizes fills in the sizes of images that were added before they were stored. Only galleries with fewer
+// sized images than image files are looked at.
+func syncImageSizes(db *gorm.DB) error {
+	var ids []uint
+	err := db.Table("galleries").
+		Joins(`LEFT JOIN image_details ON image_details.gallery_id = galleries.id
+			AND image_details.deleted_at IS NULL AND image_details.width > 0`).
+		Group("galleries.id").
+		Having("count(image_details.id) < galleries.image_count").
+		Pluck("galleries.id", &ids).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		files, err := filepath.Glob(filepath.Join(imageDir(id), "*"))
+		if err != nil {
+			return err
+		}
+		var sized []string
+		err = db.Model(&models.ImageDetail{}).Where("gallery_id = ? AND width > 0", id).Pluck("filename", &sized).Error
+		if err != nil {
+			return err
+		}
+		done := make(map[string]bool, len(sized))
+		for _, filename := range sized {
+			done[filename] = true
+		}
+		for _, file := range files {
+			if filename := filepath.Base(file); !done[filename] {
+				if err := setImageSize(db, id, filename); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// details returns the stored details of every image in a gallery, keyed by filename.
+func (is *imageService) details(galleryID uint) (map[string]models.ImageDetail, error) {
+	var details []models.ImageDetail
+	if err := is.db.Where("gallery_id = ?", galleryID).Find(&details).Error; err != nil {
+		return nil, err
+	}
+	ret := make(map[string]models.ImageDetail, len(details))
+	for _, d := range details {
+		ret[d.Filename] = d
+	}
+	return ret, nil
+}
+
+// Update saves the parts of an image that aren't stored on disk, like its caption.
+func (is *imageService) Update(i *models.Image) error {
+	var detail models.ImageDetail
+	err := is.db.Where(models.ImageDetail{GalleryID: i.GalleryID, Filename: i.Filename}).FirstOrInit(&detail).Error
+	if err != nil {
+		return err
+	}
+	detail.Caption = i.Caption
+	if err := is.db.Save(&detail).Error; err != nil {
+		return err
+	}
+	return syncSearchVector(is.db, i.GalleryID)
+}
+
 func (is *imageService) imageDir(galleryID uint) string {
+	return imageDir(galleryID)
+}
+
+func imageDir(galleryID uint) string {
 	return filepath.Join("images", "galleries",
 		fmt.Sprintf("%v", galleryID))
 }
@@ -71,5 +192,124 @@ func (is *imageService) mkImageDir(galleryID uint) (string, error) {
 }
 
 func (is *imageService) Delete(i *models.Image) error {
-	return os.Remove(i.RelativePath())
+	if err := os.Remove(i.RelativePath()); err != nil {
+		return err
+	}
+	db := is.db.Unscoped().Where("gallery_id = ? AND filename = ?", i.GalleryID, i.Filename)
+	if err := db.Delete(models.ImageDetail{}).Error; err != nil {
+		return err
+	}
+	if err := deleteVersions(is.db, i); err != nil {
+		return err
+	}
+	if err := syncSearchVector(is.db, i.GalleryID); err != nil {
+		return err
+	}
+	return syncImageCount(is.db, i.GalleryID)
+}
+
+// Copy copies the image files of one gallery into another, keeping their filenames so they stay in the same order,
+// and copies their details along with them.
+func (is *imageService) Copy(fromGalleryID, toGalleryID uint) error {
+	images, err := is.ByGalleryID(fromGalleryID)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if err := is.copyFile(img, toGalleryID); err != nil {
+			return err
+		}
+		detail := models.ImageDetail{
+			GalleryID: toGalleryID,
+			Filename:  img.Filename,
+			Caption:   img.Caption,
+			GuestName: img.GuestName,
+			Width:     img.Width,
+			Height:    img.Height,
+		}
+		if err := is.db.Create(&detail).Error; err != nil {
+			return err
+		}
+	}
+	if err := syncSearchVector(is.db, toGalleryID); err != nil {
+		return err
+	}
+	return syncImageCount(is.db, toGalleryID)
+}
+
+func (is *imageService) copyFile(img models.Image, toGalleryID uint) error {
+	src, err := os.Open(img.RelativePath())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	path, err := is.mkImageDir(toGalleryID)
+	if err != nil {
+		return err
+	}
+	dst, err := os.Create(filepath.Join(path, img.Filename))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// imageFormats maps the file extensions avatars and photos uploaded by guests can have to the format image.Decode
+// reports for them
+var imageFormats = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+	".gif":  "gif",
+}
+
+// CreateAvatar stores avatars in their own directory for each user, removing whatever was there before so that a
+// user only ever has one.
+func (is *imageService) CreateAvatar(userID uint, r io.Reader, filename string) (string, error) {
+	filename = filepath.Base(filename)
+	if imageFormats[strings.ToLower(filepath.Ext(filename))] == "" {
+		return "", models.ErrAvatarInvalid
+	}
+	avatarPath := filepath.Join("images", "avatars", fmt.Sprintf("%v", userID))
+	if err := os.RemoveAll(avatarPath); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(avatarPath, 0755); err != nil {
+		return "", err
+	}
+	dst, err := os.Create(filepath.Join(avatarPath, filename))
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, r); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// syncImageCount stores the number of images on disk for a gallery in galleries.image_count, which is what the
+// galleries index sorts on. UpdateColumn leaves updated_at alone since the gallery itself hasn't changed.
+func syncImageCount(db *gorm.DB, galleryID uint) error {
+	files, err := filepath.Glob(filepath.Join(imageDir(galleryID), "*"))
+	if err != nil {
+		return err
+	}
+	return db.Model(&models.Gallery{}).Where("id = ?", galleryID).UpdateColumn("image_count", len(files)).Error
+}
+
+// syncImageCounts fills in galleries.image_count for galleries that were created before it was stored.
+func syncImageCounts(db *gorm.DB) error {
+	var ids []uint
+	if err := db.Model(&models.Gallery{}).Where("image_count = 0").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := syncImageCount(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// An image's current version is always the file in its gallery's directory, so that it keeps being served from the
+// same path and keeps its place in the gallery, and everything that refers to it by filename - its caption, comments,
+// likes and selections - carries on referring to it. Earlier versions are moved to models.ImageVersionDir, and the
+// number of the current version is kept in the image's details.
+
+// Replace writes the new version to a temporary file first, so that the image is left as it was if the upload fails
+// part way through or isn't an image of the same type.
+func (is *imageService) Replace(i *models.Image, r io.Reader) error {
+	if err := os.MkdirAll(models.ImageVersionDir(i.GalleryID), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(models.ImageVersionDir(i.GalleryID), "upload-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	config, err := checkReplacement(tmp.Name(), i.Filename)
+	if err != nil {
+		return err
+	}
+
+	detail, err := is.detail(i)
+	if err != nil {
+		return err
+	}
+	latest, err := is.latestVersion(i)
+	if err != nil {
+		return err
+	}
+	if latest < detail.Version {
+		latest = detail.Version
+	}
+	archived := models.ImageVersion{
+		GalleryID: i.GalleryID,
+		Filename:  i.Filename,
+		Version:   detail.Version,
+	}
+	err = is.swap(i, &archived, tmp.Name(), func(tx *gorm.DB) error {
+		if err := tx.Create(&archived).Error; err != nil {
+			return err
+		}
+		detail.Version = latest + 1
+		detail.Width, detail.Height = config.Width, config.Height
+		return tx.Save(detail).Error
+	})
+	if err != nil {
+		return err
+	}
+	i.Version = detail.Version
+	return nil
+}
+
+func (is *imageService) Versions(i *models.Image) ([]models.ImageVersion, error) {
+	var versions []models.ImageVersion
+	db := is.db.Where("gallery_id = ? AND filename = ?", i.GalleryID, i.Filename).Order("version desc")
+	if err := db.Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Restore swaps the current version with the earlier one. The restored file is touched first, so that its
+// modification time, which browsers check their cached copies against, moves forward rather than back.
+func (is *imageService) Restore(i *models.Image, version int) error {
+	var earlier models.ImageVersion
+	db := is.db.Where("gallery_id = ? AND filename = ? AND version = ?", i.GalleryID, i.Filename, version)
+	if err := first(db, &earlier); err != nil {
+		return err
+	}
+	detail, err := is.detail(i)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(earlier.RelativePath(), now, now); err != nil {
+		return err
+	}
+	width, height := imageSize(earlier.RelativePath())
+	archived := models.ImageVersion{
+		GalleryID: i.GalleryID,
+		Filename:  i.Filename,
+		Version:   detail.Version,
+	}
+	err = is.swap(i, &archived, earlier.RelativePath(), func(tx *gorm.DB) error {
+		if err := tx.Create(&archived).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&earlier).Error; err != nil {
+			return err
+		}
+		detail.Version = version
+		detail.Width, detail.Height = width, height
+		return tx.Save(detail).Error
+	})
+	if err != nil {
+		return err
+	}
+	i.Version = version
+	return nil
+}
+
+func (is *imageService) CurrentVersion(galleryID uint, filename string) (int, error) {
+	detail, err := is.detail(&models.Image{GalleryID: galleryID, Filename: filename})
+	if err != nil {
+		return 0, err
+	}
+	return detail.Version, nil
+}
+
+// detail returns the image's details, or new ones if it has none yet. Images that have never been replaced are
+// version 1.
+func (is *imageService) detail(i *models.Image) (*models.ImageDetail, error) {
+	var detail models.ImageDetail
+	err := is.db.Where(models.ImageDetail{GalleryID: i.GalleryID, Filename: i.Filename}).FirstOrInit(&detail).Error
+	if err != nil {
+		return nil, err
+	}
+	if detail.Version < 1 {
+		detail.Version = 1
+	}
+	return &detail, nil
+}
+
+// latestVersion returns the highest version number of the image's earlier versions, so that a new version never
+// reuses the number of one that was restored over.
+func (is *imageService) latestVersion(i *models.Image) (int, error) {
+	var latest struct {
+		Version int
+	}
+	err := is.db.Model(&models.ImageVersion{}).Select("COALESCE(MAX(version), 0) AS version").
+		Where("gallery_id = ? AND filename = ?", i.GalleryID, i.Filename).Scan(&latest).Error
+	return latest.Version, err
+}
+
+// swap moves the image's current file out of the gallery as the archived version, moves replacement into its place,
+// and then records the change with fn in a transaction. If any step fails, the files are moved back where they were,
+// so the gallery is never left without the image.
+func (is *imageService) swap(i *models.Image, archived *models.ImageVersion, replacement string,
+	fn func(tx *gorm.DB) error) error {
+	if err := os.MkdirAll(models.ImageVersionDir(i.GalleryID), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(i.RelativePath(), archived.RelativePath()); err != nil {
+		return err
+	}
+	err := os.Rename(replacement, i.RelativePath())
+	if err == nil {
+		if err = transaction(is.db, fn); err != nil {
+			os.Rename(i.RelativePath(), replacement)
+		}
+	}
+	if err != nil {
+		os.Rename(archived.RelativePath(), i.RelativePath())
+		return err
+	}
+	return nil
+}
+
+// checkReplacement makes sure that a new version of an image is an image of the same type, since it is served under
+// the same filename, and returns its size.
+func checkReplacement(path, filename string) (image.Config, error) {
+	want := imageFormats[strings.ToLower(filepath.Ext(filename))]
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+	config, format, err := image.DecodeConfig(f)
+	if err != nil || format != want {
+		return image.Config{}, models.ErrReplacementInvalid
+	}
+	return config, nil
+}
+
+// deleteVersions removes every earlier version of the image.
+func deleteVersions(db *gorm.DB, i *models.Image) error {
+	var versions []models.ImageVersion
+	if err := db.Where("gallery_id = ? AND filename = ?", i.GalleryID, i.Filename).Find(&versions).Error; err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if err := os.Remove(v.RelativePath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return db.Unscoped().Where("gallery_id = ? AND filename = ?", i.GalleryID, i.Filename).
+		Delete(models.ImageVersion{}).Error
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements LikeDBInt interface
+
+type likeGorm struct {
+	db *gorm.DB
+}
+
+func (lg *likeGorm) ByUserID(userID uint) ([]models.Like, error) {
+	var likes []models.Like
+	db := lg.db.Where("user_id = ?", userID).Order("created_at desc")
+	if err := db.Find(&likes).Error; err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+func (lg *likeGorm) ByUserAndGallery(userID, galleryID uint) ([]models.Like, error) {
+	var likes []models.Like
+	db := lg.db.Where("user_id = ? AND gallery_id = ?", userID, galleryID)
+	if err := db.Find(&likes).Error; err != nil {
+		return nil, err
+	}
+	return likes, nil
+}
+
+func (lg *likeGorm) Find(userID, galleryID uint, filename string) (*models.Like, error) {
+	var like models.Like
+	db := lg.db.Where("user_id = ? AND gallery_id = ? AND filename = ?", userID, galleryID, filename)
+	if err := first(db, &like); err != nil {
+		return nil, err
+	}
+	return &like, nil
+}
+
+func (lg *likeGorm) Counts(galleryID uint) (map[string]int, error) {
+	rows, err := lg.db.Model(&models.Like{}).Where("gallery_id = ?", galleryID).
+		Select("filename, count(*)").Group("filename").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var filename string
+		var count int
+		if err := rows.Scan(&filename, &count); err != nil {
+			return nil, err
+		}
+		counts[filename] = count
+	}
+	return counts, rows.Err()
+}
+
+func (lg *likeGorm) Create(like *models.Like) error {
+	return lg.db.Create(like).Error
+}
+
+// Delete removes the like for good, so that liking the same thing again doesn't clash with the unique index.
+func (lg *likeGorm) Delete(id uint) error {
+	like := models.Like{Model: gorm.Model{ID: id}}
+	return lg.db.Unscoped().Delete(&like).Error
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type likeService struct {
+	interfaces.LikeDBInt
+}
+
+func NewLikeService(db *gorm.DB) interfaces.LikeServiceInt {
+	return &likeService{
+		LikeDBInt: &likeValidator{
+			LikeDBInt: &likeGorm{db},
+		},
+	}
+}
+
+func (ls *likeService) Toggle(userID, galleryID uint, filename string) (bool, error) {
+	like, err := ls.Find(userID, galleryID, filename)
+	switch err {
+	case nil:
+		return false, ls.Delete(like.ID)
+	case models.ErrNotFound:
+		like = &models.Like{
+			UserID:    userID,
+			GalleryID: galleryID,
+			Filename:  filename,
+		}
+		return true, ls.Create(like)
+	default:
+		return false, err
+	}
+}
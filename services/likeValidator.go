@@ -0,0 +1,91 @@
+package services
+
+import (
+	"path/filepath"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type likeValidator struct {
+	interfaces.LikeDBInt
+}
+
+type likeValFn func(*models.Like) error
+
+func (lv *likeValidator) ByUserID(userID uint) ([]models.Like, error) {
+	if userID <= 0 {
+		return nil, models.ErrUserIDRequired
+	}
+	return lv.LikeDBInt.ByUserID(userID)
+}
+
+func (lv *likeValidator) ByUserAndGallery(userID, galleryID uint) ([]models.Like, error) {
+	like := models.Like{UserID: userID, GalleryID: galleryID}
+	if err := runLikeValFns(&like, lv.userIDRequired, lv.galleryIDRequired); err != nil {
+		return nil, err
+	}
+	return lv.LikeDBInt.ByUserAndGallery(userID, galleryID)
+}
+
+func (lv *likeValidator) Find(userID, galleryID uint, filename string) (*models.Like, error) {
+	like := models.Like{UserID: userID, GalleryID: galleryID, Filename: filename}
+	err := runLikeValFns(&like, lv.userIDRequired, lv.galleryIDRequired, lv.filenameValid)
+	if err != nil {
+		return nil, err
+	}
+	return lv.LikeDBInt.Find(userID, galleryID, filename)
+}
+
+func (lv *likeValidator) Counts(galleryID uint) (map[string]int, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return lv.LikeDBInt.Counts(galleryID)
+}
+
+func (lv *likeValidator) Create(like *models.Like) error {
+	err := runLikeValFns(like, lv.userIDRequired, lv.galleryIDRequired, lv.filenameValid)
+	if err != nil {
+		return err
+	}
+	return lv.LikeDBInt.Create(like)
+}
+
+func (lv *likeValidator) Delete(id uint) error {
+	if id <= 0 {
+		return models.ErrIDInvalid
+	}
+	return lv.LikeDBInt.Delete(id)
+}
+
+func runLikeValFns(like *models.Like, fns ...likeValFn) error {
+	for _, fn := range fns {
+		if err := fn(like); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lv *likeValidator) userIDRequired(l *models.Like) error {
+	if l.UserID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (lv *likeValidator) galleryIDRequired(l *models.Like) error {
+	if l.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+// filenameValid makes sure a like of an image names a plain file within the gallery.
+func (lv *likeValidator) filenameValid(l *models.Like) error {
+	if l.Filename != "" && l.Filename != filepath.Base(l.Filename) {
+		return models.ErrFilenameRequired
+	}
+	return nil
+}
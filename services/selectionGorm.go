@@ -0,0 +1,71 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements SelectionDBInt interface
+
+type selectionGorm struct {
+	db *gorm.DB
+}
+
+func (sg *selectionGorm) ByID(id uint) (*models.Selection, error) {
+	var selection models.Selection
+	err := first(sg.db.Where("id = ?", id), &selection)
+	if err != nil {
+		return nil, err
+	}
+	return &selection, nil
+}
+
+func (sg *selectionGorm) ByToken(tokenHash string) (*models.Selection, error) {
+	var selection models.Selection
+	err := first(sg.db.Where("token_hash = ?", tokenHash), &selection)
+	if err != nil {
+		return nil, err
+	}
+	return &selection, nil
+}
+
+func (sg *selectionGorm) ByGalleryID(galleryID uint) ([]models.Selection, error) {
+	var selections []models.Selection
+	db := sg.db.Where("gallery_id = ?", galleryID).Order("submitted_at desc, created_at desc")
+	if err := db.Find(&selections).Error; err != nil {
+		return nil, err
+	}
+	return selections, nil
+}
+
+func (sg *selectionGorm) Create(selection *models.Selection) error {
+	return sg.db.Create(selection).Error
+}
+
+func (sg *selectionGorm) Update(selection *models.Selection) error {
+	return sg.db.Save(selection).Error
+}
+
+func (sg *selectionGorm) Images(selectionID uint) ([]models.SelectionImage, error) {
+	var images []models.SelectionImage
+	db := sg.db.Where("selection_id = ?", selectionID).Order("id")
+	if err := db.Find(&images).Error; err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+func (sg *selectionGorm) Heart(selectionID uint, filename string) error {
+	img := models.SelectionImage{
+		SelectionID: selectionID,
+		Filename:    filename,
+	}
+	return sg.db.Where(img).FirstOrCreate(&img).Error
+}
+
+// Unheart removes the row for good; there is nothing worth keeping about an image the client changed their mind on.
+func (sg *selectionGorm) Unheart(selectionID uint, filename string) error {
+	db := sg.db.Unscoped().Where("selection_id = ? AND filename = ?", selectionID, filename)
+	return db.Delete(models.SelectionImage{}).Error
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type selectionService struct {
+	interfaces.SelectionDBInt
+}
+
+func NewSelectionService(db *gorm.DB, hmacKey string) interfaces.SelectionServiceInt {
+	return &selectionService{
+		SelectionDBInt: NewSelectionValidator(&selectionGorm{db}, hash.NewHMAC(hmacKey)),
+	}
+}
+
+func (ss *selectionService) Toggle(selection *models.Selection, filename string) error {
+	if selection.Submitted() {
+		return models.ErrSelectionSubmitted
+	}
+	if selection.Hearted(filename) {
+		return ss.Unheart(selection.ID, filename)
+	}
+	return ss.Heart(selection.ID, filename)
+}
+
+func (ss *selectionService) Submit(selection *models.Selection) error {
+	if selection.Submitted() {
+		return models.ErrSelectionSubmitted
+	}
+	now := time.Now()
+	selection.SubmittedAt = &now
+	return ss.Update(selection)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/rand"
+)
+
+type selectionValidator struct {
+	interfaces.SelectionDBInt
+	hmac       hash.HMAC
+	emailRegex *regexp.Regexp
+}
+
+type selectionValFn func(*models.Selection) error
+
+func NewSelectionValidator(sdb interfaces.SelectionDBInt, hmac hash.HMAC) *selectionValidator {
+	return &selectionValidator{
+		SelectionDBInt: sdb,
+		hmac:           hmac,
+		emailRegex: regexp.MustCompile(
+			`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,16}$`),
+	}
+}
+
+func (sv *selectionValidator) ByID(id uint) (*models.Selection, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return sv.SelectionDBInt.ByID(id)
+}
+
+func (sv *selectionValidator) ByToken(token string) (*models.Selection, error) {
+	selection := models.Selection{Token: token}
+	if err := runSelectionValFns(&selection, sv.hmacToken); err != nil {
+		return nil, err
+	}
+	return sv.SelectionDBInt.ByToken(selection.TokenHash)
+}
+
+func (sv *selectionValidator) Create(selection *models.Selection) error {
+	err := runSelectionValFns(selection,
+		sv.galleryIDRequired,
+		sv.normalizeName,
+		sv.nameRequired,
+		sv.normalizeEmail,
+		sv.emailRequired,
+		sv.emailFormat,
+		sv.setTokenIfUnset,
+		sv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return sv.SelectionDBInt.Create(selection)
+}
+
+func (sv *selectionValidator) Update(selection *models.Selection) error {
+	err := runSelectionValFns(selection,
+		sv.galleryIDRequired,
+		sv.normalizeName,
+		sv.nameRequired,
+		sv.normalizeEmail,
+		sv.emailRequired,
+		sv.emailFormat,
+		sv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return sv.SelectionDBInt.Update(selection)
+}
+
+func (sv *selectionValidator) Heart(selectionID uint, filename string) error {
+	if selectionID <= 0 {
+		return models.ErrIDInvalid
+	}
+	if filename == "" {
+		return models.ErrFilenameRequired
+	}
+	return sv.SelectionDBInt.Heart(selectionID, filename)
+}
+
+func (sv *selectionValidator) Unheart(selectionID uint, filename string) error {
+	if selectionID <= 0 {
+		return models.ErrIDInvalid
+	}
+	if filename == "" {
+		return models.ErrFilenameRequired
+	}
+	return sv.SelectionDBInt.Unheart(selectionID, filename)
+}
+
+func runSelectionValFns(selection *models.Selection, fns ...selectionValFn) error {
+	for _, fn := range fns {
+		if err := fn(selection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sv *selectionValidator) galleryIDRequired(s *models.Selection) error {
+	if s.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (sv *selectionValidator) normalizeName(s *models.Selection) error {
+	s.Name = strings.TrimSpace(s.Name)
+	return nil
+}
+
+func (sv *selectionValidator) nameRequired(s *models.Selection) error {
+	if s.Name == "" {
+		return models.ErrNameRequired
+	}
+	return nil
+}
+
+func (sv *selectionValidator) normalizeEmail(s *models.Selection) error {
+	s.Email = strings.ToLower(s.Email)
+	s.Email = strings.TrimSpace(s.Email)
+	return nil
+}
+
+func (sv *selectionValidator) emailRequired(s *models.Selection) error {
+	if s.Email == "" {
+		return models.ErrEmailRequired
+	}
+	return nil
+}
+
+func (sv *selectionValidator) emailFormat(s *models.Selection) error {
+	if !sv.emailRegex.MatchString(s.Email) {
+		return models.ErrEmailInvalid
+	}
+	return nil
+}
+
+func (sv *selectionValidator) setTokenIfUnset(s *models.Selection) error {
+	if s.Token != "" {
+		return nil
+	}
+	token, err := rand.RememberToken()
+	if err != nil {
+		return err
+	}
+	s.Token = token
+	return nil
+}
+
+func (sv *selectionValidator) hmacToken(s *models.Selection) error {
+	if s.Token == "" {
+		return nil
+	}
+	s.TokenHash = sv.hmac.Hash(s.Token)
+	return nil
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 
 	"lenslocked.com/interfaces"
@@ -8,10 +10,20 @@ import (
 )
 
 type Services struct {
-	Gallery interfaces.GalleryServiceInt
-	User    interfaces.UserServiceInt
-	Image   interfaces.ImageServiceInt
-	db      *gorm.DB
+	Gallery      interfaces.GalleryServiceInt
+	User         interfaces.UserServiceInt
+	Image        interfaces.ImageServiceInt
+	Selection    interfaces.SelectionServiceInt
+	ShareLink    interfaces.ShareLinkServiceInt
+	Collaborator interfaces.CollaboratorServiceInt
+	Collection   interfaces.CollectionServiceInt
+	Stat         interfaces.StatServiceInt
+	Comment      interfaces.CommentServiceInt
+	Like         interfaces.LikeServiceInt
+	Transfer     interfaces.TransferServiceInt
+	Follow       interfaces.FollowServiceInt
+	Guest        interfaces.GuestServiceInt
+	db           *gorm.DB
 }
 
 type ServicesConfig func(*Services) error
@@ -51,16 +63,88 @@ func WithUser(pepper, hmacKey string) ServicesConfig {
 	}
 }
 
-func WithGallery() ServicesConfig {
+// WithGallery sets up the gallery service. Deleted galleries are kept in the trash for the retention period before
+// PurgeTrash deletes them forever.
+func WithGallery(pepper, hmacKey string, retention time.Duration) ServicesConfig {
+	return func(s *Services) error {
+		s.Gallery = NewGalleryService(s.db, pepper, hmacKey, retention)
+		return nil
+	}
+}
+
+func WithStat() ServicesConfig {
+	return func(s *Services) error {
+		s.Stat = NewStatService(s.db)
+		return nil
+	}
+}
+
+func WithComment() ServicesConfig {
+	return func(s *Services) error {
+		s.Comment = NewCommentService(s.db)
+		return nil
+	}
+}
+
+func WithLike() ServicesConfig {
+	return func(s *Services) error {
+		s.Like = NewLikeService(s.db)
+		return nil
+	}
+}
+
+func WithFollow() ServicesConfig {
 	return func(s *Services) error {
-		s.Gallery = NewGalleryService(s.db)
+		s.Follow = NewFollowService(s.db)
 		return nil
 	}
 }
 
 func WithImage() ServicesConfig {
 	return func(s *Services) error {
-		s.Image = NewImageService()
+		s.Image = NewImageService(s.db)
+		return nil
+	}
+}
+
+func WithSelection(hmacKey string) ServicesConfig {
+	return func(s *Services) error {
+		s.Selection = NewSelectionService(s.db, hmacKey)
+		return nil
+	}
+}
+
+func WithShareLink(hmacKey string) ServicesConfig {
+	return func(s *Services) error {
+		s.ShareLink = NewShareLinkService(s.db, hmacKey)
+		return nil
+	}
+}
+
+func WithCollaborator(hmacKey string) ServicesConfig {
+	return func(s *Services) error {
+		s.Collaborator = NewCollaboratorService(s.db, hmacKey)
+		return nil
+	}
+}
+
+func WithTransfer(hmacKey string) ServicesConfig {
+	return func(s *Services) error {
+		s.Transfer = NewTransferService(s.db, hmacKey)
+		return nil
+	}
+}
+
+func WithGuest(hmacKey string) ServicesConfig {
+	return func(s *Services) error {
+		s.Guest = NewGuestService(s.db, hmacKey)
+		return nil
+	}
+}
+
+func WithCollection() ServicesConfig {
+	return func(s *Services) error {
+		s.Collection = NewCollectionService(s.db)
 		return nil
 	}
 }
@@ -69,14 +153,83 @@ func (s *Services) Close() error {
 	return s.db.Close()
 }
 
+// tables lists every model that is stored in the database.
+func tables() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.Gallery{},
+		&models.PwReset{},
+		&models.Selection{},
+		&models.SelectionImage{},
+		&models.ShareLink{},
+		&models.Collaborator{},
+		&models.ImageDetail{},
+		&models.ImageVersion{},
+		&models.Collection{},
+		&models.GalleryStat{},
+		&models.Comment{},
+		&models.Like{},
+		&models.Transfer{},
+		&models.AuditEntry{},
+		&models.Follow{},
+		&models.GuestLink{},
+		&models.GuestUpload{},
+	}
+}
+
+// indexes creates the indexes, and the columns they cover, that can't be described with gorm struct tags.
+var indexes = []string{
+	// Usernames are optional, so only usernames that have been set need to be unique
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username)
+		WHERE username <> '' AND deleted_at IS NULL`,
+	// Stats are counted with an upsert on this
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_gallery_stats_day ON gallery_stats (gallery_id, day, filename)`,
+	// Slugs identify galleries among their owner's galleries
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_galleries_user_slug ON galleries (user_id, slug) WHERE slug <> ''`,
+	// A user can only like each gallery and image once
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_likes_user_gallery ON likes (user_id, gallery_id, filename)`,
+	// A user can only follow each user once
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_follows_follower_followee ON follows (follower_id, followee_id)`,
+	// Galleries are searched by their search_vector, see gallerySearch.go
+	`ALTER TABLE galleries ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE INDEX IF NOT EXISTS idx_galleries_search_vector ON galleries USING gin (search_vector)`,
+}
+
 func (s *Services) AutoMigrate() error {
-	return s.db.AutoMigrate(&models.User{}, &models.Gallery{}, &models.PwReset{}).Error
+	if err := s.db.AutoMigrate(tables()...).Error; err != nil {
+		return err
+	}
+	for _, sql := range indexes {
+		if err := s.db.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	if err := syncSlugs(s.db); err != nil {
+		return err
+	}
+	if err := syncSearchVectors(s.db); err != nil {
+		return err
+	}
+	if err := syncImageCounts(s.db); err != nil {
+		return err
+	}
+	return syncImageSizes(s.db)
 }
 
 func (s *Services) DestructiveReset() error {
-	err := s.db.DropTableIfExists(&models.User{}, &models.Gallery{}, &models.PwReset{}).Error
+	err := s.db.DropTableIfExists(tables()...).Error
 	if err != nil {
 		return err
 	}
 	return s.AutoMigrate()
 }
+
+// transaction runs fn in a transaction, committing it if fn succeeds and rolling it back otherwise.
+func transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	tx := db.Begin()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements ShareLinkDBInt interface
+
+type shareLinkGorm struct {
+	db *gorm.DB
+}
+
+func (slg *shareLinkGorm) ByID(id uint) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := first(slg.db.Where("id = ?", id), &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (slg *shareLinkGorm) ByToken(tokenHash string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := first(slg.db.Where("token_hash = ?", tokenHash), &link)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (slg *shareLinkGorm) ByGalleryID(galleryID uint) ([]models.ShareLink, error) {
+	var links []models.ShareLink
+	db := slg.db.Where("gallery_id = ?", galleryID).Order("created_at desc")
+	if err := db.Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (slg *shareLinkGorm) Create(link *models.ShareLink) error {
+	return slg.db.Create(link).Error
+}
+
+func (slg *shareLinkGorm) Update(link *models.ShareLink) error {
+	return slg.db.Save(link).Error
+}
+
+// ClaimView checks that the link is still valid and counts the view in one statement, so that visitors opening it at
+// the same time can't go over its limit between them, and a revoke that lands in between isn't undone.
+func (slg *shareLinkGorm) ClaimView(link *models.ShareLink) error {
+	db := slg.db.Model(link).
+		Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", time.Now()).
+		Where("max_views = 0 OR views < max_views").
+		UpdateColumn("views", gorm.Expr("views + 1"))
+	if db.Error != nil {
+		return db.Error
+	}
+	if db.RowsAffected == 0 {
+		return models.ErrShareLinkInvalid
+	}
+	link.Views++
+	return nil
+}
+
+// Revoke only sets revoked_at, so that it can't write back a stale view count.
+func (slg *shareLinkGorm) Revoke(link *models.ShareLink) error {
+	if link.Revoked() {
+		return nil
+	}
+	now := time.Now()
+	err := slg.db.Model(link).Where("revoked_at IS NULL").UpdateColumn("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	link.RevokedAt = &now
+	return nil
+}
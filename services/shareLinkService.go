@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type shareLinkService struct {
+	interfaces.ShareLinkDBInt
+}
+
+func NewShareLinkService(db *gorm.DB, hmacKey string) interfaces.ShareLinkServiceInt {
+	return &shareLinkService{
+		ShareLinkDBInt: NewShareLinkValidator(&shareLinkGorm{db}, hash.NewHMAC(hmacKey)),
+	}
+}
+
+func (sls *shareLinkService) Visit(token string) (*models.ShareLink, error) {
+	link, err := sls.ByToken(token)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, models.ErrShareLinkInvalid
+		}
+		return nil, err
+	}
+	if !link.Valid() || link.ViewsExhausted() {
+		return nil, models.ErrShareLinkInvalid
+	}
+	if err := sls.ClaimView(link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
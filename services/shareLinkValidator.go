@@ -0,0 +1,103 @@
+package services
+
+import (
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/rand"
+)
+
+type shareLinkValidator struct {
+	interfaces.ShareLinkDBInt
+	hmac hash.HMAC
+}
+
+type shareLinkValFn func(*models.ShareLink) error
+
+func NewShareLinkValidator(sldb interfaces.ShareLinkDBInt, hmac hash.HMAC) *shareLinkValidator {
+	return &shareLinkValidator{
+		ShareLinkDBInt: sldb,
+		hmac:           hmac,
+	}
+}
+
+func (slv *shareLinkValidator) ByID(id uint) (*models.ShareLink, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return slv.ShareLinkDBInt.ByID(id)
+}
+
+func (slv *shareLinkValidator) ByToken(token string) (*models.ShareLink, error) {
+	link := models.ShareLink{Token: token}
+	if err := runShareLinkValFns(&link, slv.hmacToken); err != nil {
+		return nil, err
+	}
+	return slv.ShareLinkDBInt.ByToken(link.TokenHash)
+}
+
+func (slv *shareLinkValidator) Create(link *models.ShareLink) error {
+	err := runShareLinkValFns(link,
+		slv.galleryIDRequired,
+		slv.maxViewsNonNegative,
+		slv.setTokenIfUnset,
+		slv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return slv.ShareLinkDBInt.Create(link)
+}
+
+func (slv *shareLinkValidator) Update(link *models.ShareLink) error {
+	err := runShareLinkValFns(link,
+		slv.galleryIDRequired,
+		slv.maxViewsNonNegative,
+		slv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return slv.ShareLinkDBInt.Update(link)
+}
+
+func runShareLinkValFns(link *models.ShareLink, fns ...shareLinkValFn) error {
+	for _, fn := range fns {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (slv *shareLinkValidator) galleryIDRequired(link *models.ShareLink) error {
+	if link.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (slv *shareLinkValidator) maxViewsNonNegative(link *models.ShareLink) error {
+	if link.MaxViews < 0 {
+		return models.ErrMaxViewsInvalid
+	}
+	return nil
+}
+
+func (slv *shareLinkValidator) setTokenIfUnset(link *models.ShareLink) error {
+	if link.Token != "" {
+		return nil
+	}
+	token, err := rand.RememberToken()
+	if err != nil {
+		return err
+	}
+	link.Token = token
+	return nil
+}
+
+func (slv *shareLinkValidator) hmacToken(link *models.ShareLink) error {
+	if link.Token == "" {
+		return nil
+	}
+	link.TokenHash = slv.hmac.Hash(link.Token)
+	return nil
+}
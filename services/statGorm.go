@@ -0,0 +1,42 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements StatDBInt interface
+
+type statGorm struct {
+	db *gorm.DB
+}
+
+// statDay is the day stats are counted against. Days run on UTC, wherever the server is.
+func statDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// Counting is a single upsert, so concurrent visitors can't lose each other's counts.
+const recordStatSQL = `INSERT INTO gallery_stats (gallery_id, day, filename, views, downloads) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (gallery_id, day, filename)
+	DO UPDATE SET views = gallery_stats.views + EXCLUDED.views, downloads = gallery_stats.downloads + EXCLUDED.downloads`
+
+func (sg *statGorm) RecordView(galleryID uint, filename string) error {
+	return sg.db.Exec(recordStatSQL, galleryID, statDay(time.Now()), filename, 1, 0).Error
+}
+
+func (sg *statGorm) RecordDownload(galleryID uint, filename string) error {
+	return sg.db.Exec(recordStatSQL, galleryID, statDay(time.Now()), filename, 0, 1).Error
+}
+
+func (sg *statGorm) ByGalleryID(galleryID uint, since time.Time) ([]models.GalleryStat, error) {
+	var stats []models.GalleryStat
+	db := sg.db.Where("gallery_id = ? AND day >= ?", galleryID, statDay(since)).Order("day, filename")
+	if err := db.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
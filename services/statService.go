@@ -0,0 +1,87 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type statService struct {
+	interfaces.StatDBInt
+}
+
+func NewStatService(db *gorm.DB) interfaces.StatServiceInt {
+	return &statService{
+		StatDBInt: &statValidator{
+			StatDBInt: &statGorm{db},
+		},
+	}
+}
+
+func (ss *statService) Summary(galleryID uint, days int) (*models.GalleryStats, error) {
+	today, _ := time.Parse("2006-01-02", statDay(time.Now()))
+	since := today.AddDate(0, 0, 1-days)
+	rows, err := ss.ByGalleryID(galleryID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary models.GalleryStats
+	summary.Days = make([]models.DayStats, days)
+	dayIndex := make(map[string]int, days)
+	for i := range summary.Days {
+		summary.Days[i].Day = since.AddDate(0, 0, i)
+		dayIndex[statDay(summary.Days[i].Day)] = i
+	}
+	images := make(map[string]*models.ImageStats)
+	for _, row := range rows {
+		i, ok := dayIndex[statDay(row.Day)]
+		if !ok {
+			continue
+		}
+		day := &summary.Days[i]
+		day.Downloads += row.Downloads
+		summary.Downloads += row.Downloads
+		if row.Filename == "" {
+			day.Views += row.Views
+			summary.Views += row.Views
+			continue
+		}
+		day.ImageViews += row.Views
+		img, ok := images[row.Filename]
+		if !ok {
+			img = &models.ImageStats{Filename: row.Filename}
+			images[row.Filename] = img
+		}
+		img.Views += row.Views
+		img.Downloads += row.Downloads
+	}
+
+	busiest := 0
+	for _, day := range summary.Days {
+		if day.Views > busiest {
+			busiest = day.Views
+		}
+	}
+	for i := range summary.Days {
+		if busiest > 0 {
+			summary.Days[i].Height = summary.Days[i].Views * 100 / busiest
+		}
+	}
+
+	for _, img := range images {
+		summary.Images = append(summary.Images, *img)
+	}
+	sort.Slice(summary.Images, func(i, j int) bool {
+		a, b := summary.Images[i], summary.Images[j]
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.Filename < b.Filename
+	})
+	return &summary, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"path/filepath"
+	"time"
+
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type statValidator struct {
+	interfaces.StatDBInt
+}
+
+func (sv *statValidator) RecordView(galleryID uint, filename string) error {
+	if err := sv.valid(galleryID, filename); err != nil {
+		return err
+	}
+	return sv.StatDBInt.RecordView(galleryID, filename)
+}
+
+func (sv *statValidator) RecordDownload(galleryID uint, filename string) error {
+	if filename == "" {
+		return models.ErrFilenameRequired
+	}
+	if err := sv.valid(galleryID, filename); err != nil {
+		return err
+	}
+	return sv.StatDBInt.RecordDownload(galleryID, filename)
+}
+
+func (sv *statValidator) ByGalleryID(galleryID uint, since time.Time) ([]models.GalleryStat, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return sv.StatDBInt.ByGalleryID(galleryID, since)
+}
+
+// valid makes sure stats are only ever recorded against a gallery, and a plain filename within it.
+func (sv *statValidator) valid(galleryID uint, filename string) error {
+	if galleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	if filename != "" && filename != filepath.Base(filename) {
+		return models.ErrFilenameRequired
+	}
+	return nil
+}
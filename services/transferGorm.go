@@ -0,0 +1,155 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/models"
+)
+
+// Implements TransferDBInt interface
+
+type transferGorm struct {
+	db *gorm.DB
+}
+
+func (tg *transferGorm) ByID(id uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := first(tg.db.Where("id = ?", id), &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (tg *transferGorm) ByToken(tokenHash string) (*models.Transfer, error) {
+	var transfer models.Transfer
+	if err := first(tg.db.Where("token_hash = ?", tokenHash), &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (tg *transferGorm) PendingByGalleryID(galleryID uint) (*models.Transfer, error) {
+	var transfer models.Transfer
+	db := tg.db.Where("gallery_id = ? AND accepted_at IS NULL AND cancelled_at IS NULL AND expires_at > ?",
+		galleryID, time.Now())
+	if err := first(db, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (tg *transferGorm) Create(transfer *models.Transfer) error {
+	return transaction(tg.db, func(tx *gorm.DB) error {
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+		return audit(tx, transfer.GalleryID, transfer.FromUserID, models.AuditTransferRequested,
+			"Offered to "+transfer.ToEmail)
+	})
+}
+
+// pendingTransfer picks out the transfer if it is still pending. Transfers are only ever changed through it, so that a
+// transfer that is cancelled and accepted at the same time ends up as only one of them.
+func pendingTransfer(tx *gorm.DB, transfer *models.Transfer) *gorm.DB {
+	return tx.Model(&models.Transfer{}).
+		Where("id = ? AND accepted_at IS NULL AND cancelled_at IS NULL", transfer.ID)
+}
+
+func (tg *transferGorm) Cancel(transfer *models.Transfer, userID uint) error {
+	now := time.Now()
+	err := transaction(tg.db, func(tx *gorm.DB) error {
+		db := pendingTransfer(tx, transfer).UpdateColumn("cancelled_at", now)
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return models.ErrTransferNotPending
+		}
+		return audit(tx, transfer.GalleryID, userID, models.AuditTransferCancelled,
+			"Offer to "+transfer.ToEmail+" withdrawn")
+	})
+	if err != nil {
+		return err
+	}
+	transfer.CancelledAt = &now
+	return nil
+}
+
+// Complete moves the gallery to its new owner. Share links, selections, comments and the images themselves all
+// belong to the gallery rather than its owner, so they move along with it. What doesn't move is the collection the
+// gallery was in, which still belongs to the old owner, and the new owner no longer needs to be a collaborator. If the
+// new owner already has a gallery with the same slug, the gallery gets a new one.
+func (tg *transferGorm) Complete(transfer *models.Transfer) error {
+	return transaction(tg.db, func(tx *gorm.DB) error {
+		// Accepting the transfer first also locks it until we're done, so it can't be cancelled in the meantime
+		db := pendingTransfer(tx, transfer).Where("expires_at > ?", time.Now()).UpdateColumns(map[string]interface{}{
+			"to_user_id":  transfer.ToUserID,
+			"accepted_at": transfer.AcceptedAt,
+		})
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return models.ErrTokenInvalid
+		}
+		// Only move the gallery if it still belongs to whoever offered it
+		var gallery models.Gallery
+		err := first(tx.Where("id = ? AND user_id = ?", transfer.GalleryID, transfer.FromUserID), &gallery)
+		if err == models.ErrNotFound {
+			return models.ErrTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+		taken := func(slug string) (bool, error) {
+			return slugTaken(tx, transfer.ToUserID, gallery.ID, slug)
+		}
+		slug := gallery.Slug
+		isTaken, err := taken(slug)
+		if err != nil {
+			return err
+		}
+		if isTaken {
+			if slug, err = freeSlug(gallery.Title, taken); err != nil {
+				return err
+			}
+		}
+		err = tx.Model(&models.Gallery{}).Where("id = ?", gallery.ID).UpdateColumns(map[string]interface{}{
+			"user_id":       transfer.ToUserID,
+			"collection_id": gorm.Expr("NULL"),
+			"slug":          slug,
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Unscoped().Where("gallery_id = ? AND user_id = ?", transfer.GalleryID, transfer.ToUserID).
+			Delete(&models.Collaborator{}).Error
+		if err != nil {
+			return err
+		}
+		return audit(tx, transfer.GalleryID, transfer.ToUserID, models.AuditTransferAccepted,
+			"Accepted by "+transfer.ToEmail)
+	})
+}
+
+func (tg *transferGorm) AuditTrail(galleryID uint) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	db := tg.db.Where("gallery_id = ?", galleryID).Order("created_at desc, id desc")
+	if err := db.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// audit adds an entry to a gallery's audit trail.
+func audit(db *gorm.DB, galleryID, userID uint, action, detail string) error {
+	entry := models.AuditEntry{
+		GalleryID: galleryID,
+		UserID:    userID,
+		Action:    action,
+		Detail:    detail,
+	}
+	return db.Create(&entry).Error
+}
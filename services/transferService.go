@@ -0,0 +1,44 @@
+package services
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+type transferService struct {
+	interfaces.TransferDBInt
+}
+
+func NewTransferService(db *gorm.DB, hmacKey string) interfaces.TransferServiceInt {
+	return &transferService{
+		TransferDBInt: NewTransferValidator(&transferGorm{db}, hash.NewHMAC(hmacKey)),
+	}
+}
+
+func (ts *transferService) Accept(token string, user *models.User) (*models.Transfer, error) {
+	transfer, err := ts.ByToken(token)
+	if err != nil {
+		if err == models.ErrNotFound {
+			return nil, models.ErrTokenInvalid
+		}
+		return nil, err
+	}
+	if !transfer.Pending() {
+		return nil, models.ErrTokenInvalid
+	}
+	if transfer.ToEmail != user.Email {
+		return nil, models.ErrTransferWrongUser
+	}
+	now := time.Now()
+	transfer.ToUserID = user.ID
+	transfer.AcceptedAt = &now
+	if err := ts.Complete(transfer); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
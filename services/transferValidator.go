@@ -0,0 +1,187 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+	"lenslocked.com/rand"
+)
+
+// How long the recipient has to accept a transfer
+const transferExpiry = 7 * 24 * time.Hour
+
+type transferValidator struct {
+	interfaces.TransferDBInt
+	hmac       hash.HMAC
+	emailRegex *regexp.Regexp
+}
+
+type transferValFn func(*models.Transfer) error
+
+func NewTransferValidator(tdb interfaces.TransferDBInt, hmac hash.HMAC) *transferValidator {
+	return &transferValidator{
+		TransferDBInt: tdb,
+		hmac:          hmac,
+		emailRegex: regexp.MustCompile(
+			`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,16}$`),
+	}
+}
+
+func (tv *transferValidator) ByID(id uint) (*models.Transfer, error) {
+	if id <= 0 {
+		return nil, models.ErrIDInvalid
+	}
+	return tv.TransferDBInt.ByID(id)
+}
+
+func (tv *transferValidator) ByToken(token string) (*models.Transfer, error) {
+	transfer := models.Transfer{Token: token}
+	if err := runTransferValFns(&transfer, tv.hmacToken); err != nil {
+		return nil, err
+	}
+	return tv.TransferDBInt.ByToken(transfer.TokenHash)
+}
+
+func (tv *transferValidator) PendingByGalleryID(galleryID uint) (*models.Transfer, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return tv.TransferDBInt.PendingByGalleryID(galleryID)
+}
+
+func (tv *transferValidator) Create(transfer *models.Transfer) error {
+	err := runTransferValFns(transfer,
+		tv.galleryIDRequired,
+		tv.fromUserRequired,
+		tv.normalizeEmail,
+		tv.emailRequired,
+		tv.emailFormat,
+		tv.notAlreadyPending,
+		tv.setExpiryIfUnset,
+		tv.setTokenIfUnset,
+		tv.hmacToken)
+	if err != nil {
+		return err
+	}
+	return tv.TransferDBInt.Create(transfer)
+}
+
+func (tv *transferValidator) Complete(transfer *models.Transfer) error {
+	err := runTransferValFns(transfer,
+		tv.galleryIDRequired,
+		tv.fromUserRequired,
+		tv.toUserRequired,
+		tv.notToSelf)
+	if err != nil {
+		return err
+	}
+	return tv.TransferDBInt.Complete(transfer)
+}
+
+func (tv *transferValidator) AuditTrail(galleryID uint) ([]models.AuditEntry, error) {
+	if galleryID <= 0 {
+		return nil, models.ErrGalleryIDRequired
+	}
+	return tv.TransferDBInt.AuditTrail(galleryID)
+}
+
+func runTransferValFns(transfer *models.Transfer, fns ...transferValFn) error {
+	for _, fn := range fns {
+		if err := fn(transfer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tv *transferValidator) galleryIDRequired(t *models.Transfer) error {
+	if t.GalleryID <= 0 {
+		return models.ErrGalleryIDRequired
+	}
+	return nil
+}
+
+func (tv *transferValidator) fromUserRequired(t *models.Transfer) error {
+	if t.FromUserID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (tv *transferValidator) toUserRequired(t *models.Transfer) error {
+	if t.ToUserID <= 0 {
+		return models.ErrUserIDRequired
+	}
+	return nil
+}
+
+func (tv *transferValidator) notToSelf(t *models.Transfer) error {
+	if t.ToUserID == t.FromUserID {
+		return models.ErrTransferToSelf
+	}
+	return nil
+}
+
+func (tv *transferValidator) normalizeEmail(t *models.Transfer) error {
+	t.ToEmail = strings.ToLower(t.ToEmail)
+	t.ToEmail = strings.TrimSpace(t.ToEmail)
+	return nil
+}
+
+func (tv *transferValidator) emailRequired(t *models.Transfer) error {
+	if t.ToEmail == "" {
+		return models.ErrEmailRequired
+	}
+	return nil
+}
+
+func (tv *transferValidator) emailFormat(t *models.Transfer) error {
+	if !tv.emailRegex.MatchString(t.ToEmail) {
+		return models.ErrEmailInvalid
+	}
+	return nil
+}
+
+// A gallery can only be on offer to one person at a time
+func (tv *transferValidator) notAlreadyPending(t *models.Transfer) error {
+	_, err := tv.TransferDBInt.PendingByGalleryID(t.GalleryID)
+	switch err {
+	case models.ErrNotFound:
+		return nil
+	case nil:
+		return models.ErrTransferPending
+	default:
+		return err
+	}
+}
+
+func (tv *transferValidator) setExpiryIfUnset(t *models.Transfer) error {
+	if t.ExpiresAt.IsZero() {
+		t.ExpiresAt = time.Now().Add(transferExpiry)
+	}
+	return nil
+}
+
+func (tv *transferValidator) setTokenIfUnset(t *models.Transfer) error {
+	if t.Token != "" {
+		return nil
+	}
+	token, err := rand.RememberToken()
+	if err != nil {
+		return err
+	}
+	t.Token = token
+	return nil
+}
+
+func (tv *transferValidator) hmacToken(t *models.Transfer) error {
+	if t.Token == "" {
+		return nil
+	}
+	t.TokenHash = tv.hmac.Hash(t.Token)
+	return nil
+}
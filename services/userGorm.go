@@ -38,6 +38,15 @@ func (ug *UserGorm) ByRemember(rememberHash string) (*models.User, error) {
 	return &user, nil
 }
 
+func (ug *UserGorm) ByUsername(username string) (*models.User, error) {
+	var user models.User
+	err := first(ug.db.Where("username = ?", username), &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func first(db *gorm.DB, dst interface{}) error {
 	err := db.First(dst).Error
 	if err == gorm.ErrRecordNotFound {
@@ -3,8 +3,10 @@ package services
 import (
 	"errors"
 	"golang.org/x/crypto/bcrypt"
+	"net/url"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"lenslocked.com/hash"
 	"lenslocked.com/interfaces"
@@ -17,11 +19,25 @@ type userValidator struct {
 	// Whatever instantiates the UserDBInt field is what uv.UserDBInt refers to
 	// So to enable interface chaining from a uv to ug, the UserDBInt field will be instantiated to a ug
 	interfaces.UserDBInt
-	hmac       hash.HMAC
-	pepper     string
-	emailRegex *regexp.Regexp
+	hmac          hash.HMAC
+	pepper        string
+	emailRegex    *regexp.Regexp
+	usernameRegex *regexp.Regexp
 }
 
+// reservedUsernames can't be used as usernames since they would be confusing next to the site's own pages and
+// addresses.
+var reservedUsernames = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true, "api": true, "assets": true,
+	"contact": true, "embed": true, "feed": true, "forgot": true, "galleries": true, "help": true, "images": true,
+	"invitations": true, "lenslocked": true, "login": true, "logout": true, "mail": true, "oembed": true,
+	"reset": true, "root": true, "settings": true, "signup": true, "sitemap": true, "staff": true, "support": true,
+	"webmaster": true, "www": true,
+}
+
+// maxBioLength is the most characters a user's bio can have
+const maxBioLength = 500
+
 type userValFn func(*models.User) error
 
 func NewUserValidator(udb interfaces.UserDBInt, hmac hash.HMAC, pepper string) *userValidator {
@@ -31,6 +47,8 @@ func NewUserValidator(udb interfaces.UserDBInt, hmac hash.HMAC, pepper string) *
 		pepper:    pepper,
 		emailRegex: regexp.MustCompile(
 			`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,16}$`),
+		usernameRegex: regexp.MustCompile(
+			`^[a-z0-9][a-z0-9_\-]{1,28}[a-z0-9]$`),
 	}
 }
 
@@ -66,6 +84,16 @@ func (uv *userValidator) ByRemember(token string) (*models.User, error) {
 	return uv.UserDBInt.ByRemember(user.RememberHash)
 }
 
+func (uv *userValidator) ByUsername(username string) (*models.User, error) {
+	user := models.User{
+		Username: username,
+	}
+	if err := runUserValFns(&user, uv.normalizeUsername); err != nil {
+		return nil, err
+	}
+	return uv.UserDBInt.ByUsername(user.Username)
+}
+
 func (uv *userValidator) Create(user *models.User) error {
 	if err := runUserValFns(user,
 		uv.passwordRequired,
@@ -79,7 +107,14 @@ func (uv *userValidator) Create(user *models.User) error {
 		uv.normalizeEmail,
 		uv.requireEmail,
 		uv.emailFormat,
-		uv.emailIsAvail); err != nil {
+		uv.emailIsAvail,
+		uv.normalizeUsername,
+		uv.usernameFormat,
+		uv.usernameNotReserved,
+		uv.usernameIsAvail,
+		uv.bioMaxLength,
+		uv.normalizeWebsite,
+		uv.websiteFormat); err != nil {
 		return err
 	}
 
@@ -105,7 +140,14 @@ func (uv *userValidator) Update(user *models.User) error {
 		uv.normalizeEmail,
 		uv.requireEmail,
 		uv.emailFormat,
-		uv.emailIsAvail); err != nil {
+		uv.emailIsAvail,
+		uv.normalizeUsername,
+		uv.usernameFormat,
+		uv.usernameNotReserved,
+		uv.usernameIsAvail,
+		uv.bioMaxLength,
+		uv.normalizeWebsite,
+		uv.websiteFormat); err != nil {
 		return err
 	}
 
@@ -238,6 +280,81 @@ func (uv *userValidator) emailIsAvail(user *models.User) error {
 	return nil
 }
 
+// Normalize usernames
+func (uv *userValidator) normalizeUsername(user *models.User) error {
+	user.Username = strings.ToLower(user.Username)
+	user.Username = strings.TrimSpace(user.Username)
+	user.Username = strings.TrimPrefix(user.Username, "@")
+	return nil
+}
+
+// Validate username format. Usernames are optional, so an empty one is fine.
+func (uv *userValidator) usernameFormat(user *models.User) error {
+	if user.Username == "" {
+		return nil
+	}
+	if !uv.usernameRegex.MatchString(user.Username) {
+		return models.ErrUsernameInvalid
+	}
+	return nil
+}
+
+// Validate the username isn't one we keep for ourselves
+func (uv *userValidator) usernameNotReserved(user *models.User) error {
+	if reservedUsernames[user.Username] {
+		return models.ErrUsernameReserved
+	}
+	return nil
+}
+
+// Validate unique username, the same way emailIsAvail does for email addresses
+func (uv *userValidator) usernameIsAvail(user *models.User) error {
+	if user.Username == "" {
+		return nil
+	}
+	existing, err := uv.ByUsername(user.Username)
+	if err == models.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.ID != existing.ID {
+		return models.ErrUsernameTaken
+	}
+	return nil
+}
+
+// Validate length of bio
+func (uv *userValidator) bioMaxLength(user *models.User) error {
+	if utf8.RuneCountInString(user.Bio) > maxBioLength {
+		return models.ErrBioTooLong
+	}
+	return nil
+}
+
+// Normalize website addresses, assuming https if the user left the scheme off
+func (uv *userValidator) normalizeWebsite(user *models.User) error {
+	user.Website = strings.TrimSpace(user.Website)
+	if user.Website != "" && !strings.Contains(user.Website, "://") {
+		user.Website = "https://" + user.Website
+	}
+	return nil
+}
+
+// Validate the website is a http or https address. Anything else, like a javascript: URL, could do harm when it is
+// linked to from the user's profile.
+func (uv *userValidator) websiteFormat(user *models.User) error {
+	if user.Website == "" {
+		return nil
+	}
+	u, err := url.Parse(user.Website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.ErrWebsiteInvalid
+	}
+	return nil
+}
+
 // Validate length of password
 func (uv *userValidator) passwordMinLength(user *models.User) error {
 	if user.Password == "" {
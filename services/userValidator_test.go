@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+
+	"lenslocked.com/hash"
+	"lenslocked.com/interfaces"
+	"lenslocked.com/models"
+)
+
+// usernameDB is a UserDBInt that only knows how to look users up by username.
+type usernameDB struct {
+	interfaces.UserDBInt
+	users map[string]*models.User
+}
+
+func (db *usernameDB) ByUsername(username string) (*models.User, error) {
+	if user, ok := db.users[username]; ok {
+		return user, nil
+	}
+	return nil, models.ErrNotFound
+}
+
+func TestUsernameValidation(t *testing.T) {
+	taken := &models.User{Username: "taken"}
+	taken.ID = 1
+	uv := NewUserValidator(&usernameDB{users: map[string]*models.User{"taken": taken}}, hash.NewHMAC("secret"), "")
+
+	tests := []struct {
+		username string
+		userID   uint
+		want     string
+		err      error
+	}{
+		{"", 2, "", nil},
+		{"  @Ada_Lovelace ", 2, "ada_lovelace", nil},
+		{"abc", 2, "abc", nil},
+		{"a-b_c", 2, "a-b_c", nil},
+		{"ab", 2, "ab", models.ErrUsernameInvalid},
+		{"abcdefghijklmnopqrstuvwxyz12345", 2, "abcdefghijklmnopqrstuvwxyz12345", models.ErrUsernameInvalid},
+		{"-abc", 2, "-abc", models.ErrUsernameInvalid},
+		{"abc_", 2, "abc_", models.ErrUsernameInvalid},
+		{"a.b", 2, "a.b", models.ErrUsernameInvalid},
+		{"añb", 2, "añb", models.ErrUsernameInvalid},
+		{"Admin", 2, "admin", models.ErrUsernameReserved},
+		{"galleries", 2, "galleries", models.ErrUsernameReserved},
+		{"taken", 2, "taken", models.ErrUsernameTaken},
+		// A user keeping their own username isn't taking it from anyone
+		{"taken", 1, "taken", nil},
+	}
+	for _, test := range tests {
+		user := &models.User{Username: test.username}
+		user.ID = test.userID
+		err := runUserValFns(user, uv.normalizeUsername, uv.usernameFormat, uv.usernameNotReserved, uv.usernameIsAvail)
+		if err != test.err {
+			t.Errorf("validating username %q for user %d returned %v, want %v", test.username, test.userID, err, test.err)
+		}
+		if user.Username != test.want {
+			t.Errorf("username %q was normalized to %q, want %q", test.username, user.Username, test.want)
+		}
+	}
+}
@@ -21,6 +21,7 @@ const (
 type Data struct {
 	Alert *Alert
 	User  *models.User
+	Meta  *Meta
 	Yield interface{}
 }
 
@@ -0,0 +1,64 @@
+package views
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// descriptionLength is the longest description Summarize makes. Search engines and link previews cut off anything
+// much longer.
+const descriptionLength = 200
+
+// Meta describes a page to search engines and to sites that show previews of links, like chat apps and social
+// networks. The bootstrap layout turns it into the page's title and <meta> and <link> tags.
+type Meta struct {
+	Title       string
+	Description string
+	// Image and URL are absolute URLs. URL is the canonical address of the page.
+	Image string
+	URL   string
+	// Type is the OpenGraph type of the page, eg "profile". It defaults to "website".
+	Type string
+	// NoIndex asks search engines to leave the page out of their results
+	NoIndex bool
+	// Links are extra <link> tags, like feeds of the page
+	Links []MetaLink
+}
+
+// MetaLink is a <link> tag in the head of a page.
+type MetaLink struct {
+	Rel   string
+	Type  string
+	Href  string
+	Title string
+}
+
+// OGType returns the OpenGraph type of the page.
+func (m *Meta) OGType() string {
+	if m.Type == "" {
+		return "website"
+	}
+	return m.Type
+}
+
+// TwitterCard returns the kind of card Twitter should show for the page, with a large image if there is one.
+func (m *Meta) TwitterCard() string {
+	if m.Image != "" {
+		return "summary_large_image"
+	}
+	return "summary"
+}
+
+// Summarize shortens text written by users, like gallery descriptions, so it can be used as a page description.
+// Whitespace is collapsed and long text is cut at the end of a word.
+func Summarize(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= descriptionLength {
+		return text
+	}
+	cut := string([]rune(text)[:descriptionLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
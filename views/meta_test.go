@@ -0,0 +1,39 @@
+package views
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSummarize(t *testing.T) {
+	long := strings.Repeat("word ", 60)
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"short", "A day in Rome.", "A day in Rome."},
+		{"whitespace", "  A day\n\nin\tRome.  ", "A day in Rome."},
+		{"exact length", strings.Repeat("a", descriptionLength), strings.Repeat("a", descriptionLength)},
+		{"cut at a word", long, strings.TrimSpace(long[:descriptionLength]) + "…"},
+		{"one long word", strings.Repeat("a", descriptionLength+1), strings.Repeat("a", descriptionLength) + "…"},
+		// Length is counted in characters, so multi-byte text isn't cut short or split inside a character
+		{"multi-byte", strings.Repeat("é", descriptionLength), strings.Repeat("é", descriptionLength)},
+		{"multi-byte cut", strings.Repeat("日本 ", 80), strings.TrimSpace(strings.Repeat("日本 ", 66)) + "…"},
+	}
+	for _, test := range tests {
+		got := Summarize(test.text)
+		if got != test.want {
+			t.Errorf("%s: Summarize(%q) = %q, want %q", test.name, test.text, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("%s: Summarize(%q) = %q, which isn't valid UTF-8", test.name, test.text, got)
+		}
+		if n := utf8.RuneCountInString(strings.TrimSuffix(got, "…")); n > descriptionLength {
+			t.Errorf("%s: Summarize(%q) is %d characters long, want at most %d", test.name, test.text, n,
+				descriptionLength)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package views
+
+import "net/url"
+
+// Pagination holds the links rendered by the "pagination" template. A link is empty when there is no page in that
+// direction.
+type Pagination struct {
+	PrevURL string
+	NextURL string
+}
+
+// NewPagination builds the links for a list paged with cursors. The links keep the rest of the current query
+// string, such as sorting and filters, and pass the cursor in either the "before" or "after" parameter.
+func NewPagination(u *url.URL, prevCursor, nextCursor string) *Pagination {
+	var p Pagination
+	if prevCursor != "" {
+		p.PrevURL = pageURL(u, "before", prevCursor)
+	}
+	if nextCursor != "" {
+		p.NextURL = pageURL(u, "after", nextCursor)
+	}
+	return &p
+}
+
+func pageURL(u *url.URL, param, cursor string) string {
+	q := u.Query()
+	q.Del("before")
+	q.Del("after")
+	q.Set(param, cursor)
+	ret := url.URL{Path: u.Path, RawQuery: q.Encode()}
+	return ret.String()
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gorilla/csrf"
 
 	"lenslocked.com/context"
+	"lenslocked.com/markdown"
 )
 
 type View struct {
@@ -41,6 +42,8 @@ func NewView(layout string, files ...string) *View {
 		"pathEscape": func(s string) string {
 			return url.PathEscape(s)
 		},
+		// Renders Markdown written by users to sanitised HTML
+		"markdown": markdown.Render,
 		"isLoggedIn": func() bool {
 			return false
 		},
@@ -68,6 +71,11 @@ func layoutFiles() []string {
 
 // Abstract away Template.ExecuteTemplate
 func (v *View) Render(w http.ResponseWriter, r *http.Request, data interface{}) {
+	v.RenderStatus(w, r, http.StatusOK, data)
+}
+
+// RenderStatus renders the view like Render, but responds with the given status code instead of 200 OK.
+func (v *View) RenderStatus(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
 	w.Header().Set("Content-Type", "text/html")
 	var vd Data
 	switch d := data.(type) {
@@ -113,6 +121,7 @@ func (v *View) Render(w http.ResponseWriter, r *http.Request, data interface{})
 			http.StatusInternalServerError)
 		return
 	}
+	w.WriteHeader(status)
 	io.Copy(w, &buf)
 }
 